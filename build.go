@@ -12,13 +12,7 @@ var _ QueryBuilder = (*Fragment)(nil)
 
 // BuildQuery builds the fragment as full query.
 func (f *Fragment) BuildQuery(bindVarStyle syntax.BindVarStyle) (query string, args []any, err error) {
-	ctx := NewContext(bindVarStyle)
-	query, err = f.BuildFragment(ctx)
-	if err != nil {
-		return "", nil, err
-	}
-	args = ctx.Args()
-	return query, args, nil
+	return BuildQuery(f, bindVarStyle)
 }
 
 // BuildFragment builds the fragment with context.
@@ -32,14 +26,16 @@ func (f *Fragment) BuildFragment(ctx *Context) (string, error) {
 	ctx = contextWithFragment(ctx, f)
 	body, err := build(ctx, f)
 	if err != nil {
-		return "", err
+		return "", newBuildError(f, err)
 	}
 	fc, err := ctx.mustFragment()
 	if err != nil {
 		return "", err
 	}
 	if err := fc.checkUsage(); err != nil {
-		return "", fmt.Errorf("build '%s': %w", f.Raw, err)
+		if err := ctx.handleUnused(f, newBuildError(f, err)); err != nil {
+			return "", err
+		}
 	}
 	body = strings.TrimSpace(body)
 	if body == "" {
@@ -57,48 +53,146 @@ func (f *Fragment) BuildFragment(ctx *Context) (string, error) {
 
 // build builds the fragment
 func build(ctx *Context, fragment *Fragment) (string, error) {
-	clause, err := syntax.Parse(fragment.Raw)
-	if err != nil {
-		return "", fmt.Errorf("parse '%s': %w", fragment.Raw, err)
+	if err := fragment.checkNames(); err != nil {
+		return "", err
 	}
-	built, err := buildClause(ctx, clause)
+	clause, err := ctx.parse(fragment.Raw)
 	if err != nil {
-		return "", fmt.Errorf("build '%s': %w", fragment.Raw, err)
+		return "", err
 	}
-	return built, nil
+	return buildClause(ctx, clause)
 }
 
 // buildClause builds the parsed clause within current context.
 func buildClause(ctx *Context, clause *syntax.Clause) (string, error) {
+	return buildExprs(ctx, clause.ExprList)
+}
+
+// buildExprs builds the expressions within current context.
+func buildExprs(ctx *Context, exprs []syntax.Expr) (string, error) {
 	b := new(strings.Builder)
-	for _, decl := range clause.ExprList {
-		switch expr := decl.(type) {
-		case *syntax.PlainExpr:
-			b.WriteString(expr.Text)
-		case *syntax.BindVarExpr:
-			fc, err := ctx.mustFragment()
-			if err != nil {
-				return "", err
-			}
-			if expr.Index < 1 || expr.Index > len(fc.Args) {
-				return "", fmt.Errorf("invalid bind var index %d", expr.Index)
-			}
-			s, err := fc.Args[expr.Index-1].BuildFragment(ctx)
-			if err != nil {
-				return "", err
-			}
-			b.WriteString(s)
-		case *syntax.FuncCallExpr:
-			s, err := evalFunction(ctx, expr.Name, expr.Args)
-			if err != nil {
-				return "", err
-			}
-			b.WriteString(s)
-		case *syntax.FuncExpr:
-			return "", fmt.Errorf("unexpected function value at %s, forgot to call it?", expr.Pos())
-		default:
-			return "", fmt.Errorf("unknown expression type %T", expr)
+	for _, expr := range exprs {
+		s, err := buildExpr(ctx, expr)
+		if err != nil {
+			return "", &posError{pos: expr.Pos(), err: err}
 		}
+		b.WriteString(s)
 	}
 	return b.String(), nil
 }
+
+// buildExpr builds the expression within current context.
+func buildExpr(ctx *Context, expr syntax.Expr) (string, error) {
+	switch expr := expr.(type) {
+	case *syntax.PlainExpr:
+		return expr.Text, nil
+	case *syntax.BindVarExpr:
+		fc, err := ctx.mustFragment()
+		if err != nil {
+			return "", err
+		}
+		if expr.Index < 1 || expr.Index > len(fc.Args) {
+			return "", &BindVarError{Index: expr.Index, NArgs: len(fc.Args)}
+		}
+		return fc.Args[expr.Index-1].BuildFragment(ctx)
+	case *syntax.FuncCallExpr:
+		return buildCall(ctx, expr)
+	case *syntax.FuncExpr:
+		return "", fmt.Errorf("unexpected function value #%s, forgot to call it?", expr.Name)
+	case *syntax.IfExpr:
+		return buildIf(ctx, expr)
+	default:
+		return "", fmt.Errorf("unknown expression type %T", expr)
+	}
+}
+
+// buildIf builds the branch of the #if section selected by the condition.
+//
+// The references in the other branch are reported as used, so that they
+// don't fail the usage check.
+func buildIf(ctx *Context, expr *syntax.IfExpr) (string, error) {
+	ok, built, err := evalCondition(ctx, expr.Cond)
+	if err != nil {
+		return "", fmt.Errorf("#if condition: %w", err)
+	}
+	taken, skipped := expr.Then, expr.Else
+	if !ok {
+		taken, skipped = skipped, taken
+	}
+	if err := reportReferenced(ctx, skipped); err != nil {
+		return "", err
+	}
+	if built != nil {
+		fc, _ := ctx.Fragment()
+		if call := newBuiltCall(fc, expr.Cond, taken, built.text, built.store); call != nil {
+			ctx = contextWithBuiltCall(ctx, call)
+		}
+	}
+	return buildExprs(ctx, taken)
+}
+
+// builtCondition is the built result of a condition returning string.
+type builtCondition struct {
+	text  string
+	store *recordArgStore
+}
+
+// evalCondition evaluates the condition of #if, without committing any
+// args. For the functions returning string, the built result is returned
+// to be reused by the branches.
+func evalCondition(ctx *Context, cond *syntax.FuncCallExpr) (bool, *builtCondition, error) {
+	f, ok := ctx.fn(cond.Name)
+	if !ok {
+		return false, nil, &UnknownFuncError{Name: cond.Name}
+	}
+	if f.builtin && cond.Name == "arg" {
+		// an arg is always built as a bindvar, test its value instead.
+		ok, err := evalCond(ctx, builtInArgCond, cond.Args)
+		return ok, nil, err
+	}
+	if f.outBool || f.nOut == 0 {
+		ok, err := evalCond(contextWithDiscardArgs(ctx), f, cond.Args)
+		return ok, nil, err
+	}
+	store := &recordArgStore{}
+	text, err := buildCall(contextWithArgStore(ctx, store), cond)
+	if err != nil {
+		return false, nil, err
+	}
+	return text != "", &builtCondition{text: text, store: store}, nil
+}
+
+// reportReferenced reports the args and fragments referenced
+// by the expressions as used, without building them. If a custom
+// function is called, the usage check of the fragment is skipped,
+// like Validate does.
+func reportReferenced(ctx *Context, exprs []syntax.Expr) error {
+	fc, err := ctx.mustFragment()
+	if err != nil {
+		return err
+	}
+	v := &fragmentValidator{
+		ctx:       ctx,
+		f:         fc.Fragment,
+		args:      make([]bool, len(fc.Args)),
+		fragments: make([]bool, len(fc.Fragments)),
+	}
+	for _, expr := range exprs {
+		// errors of the skipped branch are left to Validate
+		_ = v.expr(expr)
+	}
+	if v.usageUnknown {
+		fc.usageUnknown = true
+	}
+	for i, used := range v.args {
+		if used {
+			fc.Args[i].ReportUsed()
+		}
+	}
+	for i, used := range v.fragments {
+		if used {
+			fc.Fragments[i].ReportUsed()
+		}
+	}
+	return nil
+}
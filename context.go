@@ -1,40 +1,113 @@
 package sqlf
 
 import (
+	"context"
+
 	"github.com/qjebbs/go-sqlf/v2/syntax"
 )
 
 // Context is the global context shared between all fragments building.
 type Context struct {
 	bindVarStyle syntax.BindVarStyle
-	argStore     argStore
+	argStore     ArgStore
+
+	usage     UsagePolicy
+	usageHook func(err error)
+
+	expansion  SliceExpansion
+	emptySlice string
+
+	parseOptions []syntax.ParseOption
+
+	hook    BuildHook
+	hookSet bool
+	goCtx   context.Context
 
 	parent *Context
 	funcs  map[string]*funcInfo
 	frag   *FragmentContext
+	values *contextEntry
+	call   *builtCall
+}
+
+// ContextOption is the option of NewContext.
+type ContextOption func(*contextOptions)
+
+type contextOptions struct {
+	argStore   ArgStore
+	dedupe     Dedupe
+	usage      UsagePolicy
+	usageHook  func(err error)
+	expansion  SliceExpansion
+	emptySlice string
+	parse      []syntax.ParseOption
+
+	buildHook    BuildHook
+	buildHookSet bool
+	goCtx        context.Context
+}
+
+// WithArgStore sets the ArgStore of the context, which overrides
+// the built-in one of the bindvar style.
+func WithArgStore(s ArgStore) ContextOption {
+	return func(o *contextOptions) {
+		o.argStore = s
+	}
+}
+
+// WithDedupe sets the de-duplication policy of the built-in ArgStore,
+// it takes no effect when WithArgStore is used.
+func WithDedupe(d Dedupe) ContextOption {
+	return func(o *contextOptions) {
+		o.dedupe = d
+	}
+}
+
+// WithParseOptions sets the options to parse the Raw of fragments,
+// e.g. syntax.WithBackslashEscapes() for MySQL.
+func WithParseOptions(options ...syntax.ParseOption) ContextOption {
+	return func(o *contextOptions) {
+		o.parse = append(o.parse, options...)
+	}
+}
+
+// parse parses the raw with the parse options of the context.
+func (c *Context) parse(raw string) (*syntax.Clause, error) {
+	return syntax.Parse(raw, c.root().parseOptions...)
 }
 
 // NewContext returns a new context.
-func NewContext(bindVarStyle syntax.BindVarStyle) *Context {
-	ctx := newEmptyContext(bindVarStyle)
+func NewContext(bindVarStyle syntax.BindVarStyle, options ...ContextOption) *Context {
+	ctx := newEmptyContext(bindVarStyle, options...)
 	ctx.bindVarStyle = bindVarStyle
-	err := addValueFuncs(ctx.funcs, builtInFuncs)
-	if err != nil {
-		// should never happen for builtInFuncs
-		panic(err)
-	}
+	ctx.funcs = builtInEnv.funcs
 	return ctx
 }
 
-func newEmptyContext(bindVarStyle syntax.BindVarStyle) *Context {
-	var argStore argStore
-	if bindVarStyle == syntax.Dollar {
-		argStore = newDollarArgStore()
-	} else {
-		argStore = newQuestionArgStore()
+func newEmptyContext(bindVarStyle syntax.BindVarStyle, options ...ContextOption) *Context {
+	opts := &contextOptions{}
+	for _, opt := range options {
+		opt(opts)
+	}
+	argStore := opts.argStore
+	if argStore == nil {
+		if bindVarStyle == syntax.Dollar {
+			argStore = NewDollarArgStore(opts.dedupe)
+		} else {
+			argStore = NewQuestionArgStore()
+		}
 	}
 	return &Context{
-		funcs:    make(map[string]*funcInfo),
-		argStore: argStore,
+		argStore:   argStore,
+		usage:      opts.usage,
+		usageHook:  opts.usageHook,
+		expansion:  opts.expansion,
+		emptySlice: opts.emptySlice,
+
+		parseOptions: opts.parse,
+
+		hook:    opts.buildHook,
+		hookSet: opts.buildHookSet,
+		goCtx:   opts.goCtx,
 	}
 }
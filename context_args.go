@@ -1,12 +1,13 @@
 package sqlf
 
 import (
+	"reflect"
 	"strconv"
 )
 
 // Args returns the built args of the context.
 func (c *Context) Args() []any {
-	return c.root().argStore.Args()
+	return c.store().Args()
 }
 
 // CommitArg commits an built arg to the context and returns the built bindvar.
@@ -14,19 +15,62 @@ func (c *Context) Args() []any {
 // It's used usually in the implementation of a FragmentBuilder,
 // most users don't need to care about it.
 func (c *Context) CommitArg(arg any) string {
-	return c.root().argStore.CommitArg(arg)
+	return c.store().CommitArg(arg)
 }
 
-type argStore interface {
+// store returns the nearest ArgStore in the context chain.
+func (c *Context) store() ArgStore {
+	s, _ := contextValue(c, func(c *Context) (ArgStore, bool) {
+		return c.argStore, c.argStore != nil
+	})
+	return s
+}
+
+// contextWithDiscardArgs returns a new context discarding the committed args,
+// which is used to build fragments for testing, e.g. the conditions of #if.
+func contextWithDiscardArgs(ctx *Context) *Context {
+	return contextWithArgStore(ctx, discardArgStore{})
+}
+
+// ArgStore is the storage of the built args, which decides the
+// bindvar of an arg, and whether args are de-duplicated.
+//
+// Implement it and use WithArgStore to plug in your own strategy.
+// An ArgStore must accept any arg acceptable by the database driver,
+// including the unhashable ones like []byte.
+type ArgStore interface {
+	// Args returns the committed args.
 	Args() []any
+	// CommitArg commits an arg and returns its bindvar.
 	CommitArg(arg any) string
 }
 
+// Dedupe is the de-duplication policy of args.
+type Dedupe int
+
+// Dedupe policies.
+const (
+	// DedupeByValue de-duplicates args with equal comparable values,
+	// args that are not comparable, e.g. []byte, are never de-duplicated.
+	DedupeByValue Dedupe = iota
+	// DedupeOff disables de-duplication.
+	DedupeOff
+	// DedupeByPointer de-duplicates args referencing the same memory,
+	// that is pointers, maps, slices (with the same length) and channels.
+	// Other args are never de-duplicated.
+	DedupeByPointer
+)
+
+var _ ArgStore = (*questionArgStore)(nil)
+var _ ArgStore = (*dollarArgStore)(nil)
+
 type questionArgStore struct {
 	args []any
 }
 
-func newQuestionArgStore() *questionArgStore {
+// NewQuestionArgStore returns a new ArgStore for the syntax.Question style,
+// which never de-duplicates args.
+func NewQuestionArgStore() ArgStore {
 	return &questionArgStore{}
 }
 
@@ -39,14 +83,29 @@ func (s *questionArgStore) CommitArg(arg any) string {
 	return "?"
 }
 
+// discardArgStore discards all the committed args.
+type discardArgStore struct{}
+
+func (discardArgStore) Args() []any {
+	return nil
+}
+
+func (discardArgStore) CommitArg(arg any) string {
+	return "?"
+}
+
 type dollarArgStore struct {
-	args []any
-	dict map[any]int
+	args   []any
+	dedupe Dedupe
+	dict   map[any]int
 }
 
-func newDollarArgStore() *dollarArgStore {
+// NewDollarArgStore returns a new ArgStore for the syntax.Dollar style,
+// with the de-duplication policy d.
+func NewDollarArgStore(d Dedupe) ArgStore {
 	return &dollarArgStore{
-		dict: make(map[any]int),
+		dedupe: d,
+		dict:   make(map[any]int),
 	}
 }
 
@@ -55,11 +114,75 @@ func (s *dollarArgStore) Args() []any {
 }
 
 func (s *dollarArgStore) CommitArg(arg any) string {
-	if i, ok := s.dict[arg]; ok {
-		return "$" + strconv.Itoa(i)
+	key, ok := s.key(arg)
+	if ok {
+		if i, found := s.dict[key]; found {
+			return "$" + strconv.Itoa(i)
+		}
 	}
 	i := len(s.args) + 1
-	s.dict[arg] = i
 	s.args = append(s.args, arg)
+	if ok {
+		s.dict[key] = i
+	}
 	return "$" + strconv.Itoa(i)
 }
+
+// key returns the dedupe key of the arg, ok is false if
+// the arg should not be de-duplicated.
+func (s *dollarArgStore) key(arg any) (key any, ok bool) {
+	switch s.dedupe {
+	case DedupeByValue:
+		if arg == nil || !hashable(arg) {
+			return nil, false
+		}
+		return arg, true
+	case DedupeByPointer:
+		return pointerKeyOf(arg)
+	default:
+		return nil, false
+	}
+}
+
+// hashable reports whether v can be used as a map key without panic.
+//
+// A comparable type may still hold incomparable values in interface
+// fields, e.g. struct{ v any }{[]int{}}, so it's checked by a trial.
+func hashable(v any) (ok bool) {
+	if !reflect.TypeOf(v).Comparable() {
+		return false
+	}
+	defer func() {
+		if recover() != nil {
+			ok = false
+		}
+	}()
+	_ = v == v
+	return true
+}
+
+type pointerKey struct {
+	typ reflect.Type
+	ptr uintptr
+	len int
+}
+
+func pointerKeyOf(arg any) (pointerKey, bool) {
+	if arg == nil {
+		return pointerKey{}, false
+	}
+	v := reflect.ValueOf(arg)
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Chan, reflect.UnsafePointer:
+		if v.IsNil() {
+			return pointerKey{}, false
+		}
+		return pointerKey{typ: v.Type(), ptr: v.Pointer()}, true
+	case reflect.Slice:
+		if v.IsNil() {
+			return pointerKey{}, false
+		}
+		return pointerKey{typ: v.Type(), ptr: v.Pointer(), len: v.Len()}, true
+	}
+	return pointerKey{}, false
+}
@@ -0,0 +1,94 @@
+package sqlf_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/qjebbs/go-sqlf/v2"
+	"github.com/qjebbs/go-sqlf/v2/syntax"
+)
+
+func TestArgStoreDedupe(t *testing.T) {
+	t.Parallel()
+	type wrapper struct{ v any }
+	bytes := []byte("foo")
+	slice := []int{1, 2}
+	ptr := new(int)
+	testCases := []struct {
+		name     string
+		dedupe   sqlf.Dedupe
+		args     []any
+		want     string
+		wantArgs []any
+	}{
+		{
+			name:     "by value",
+			dedupe:   sqlf.DedupeByValue,
+			args:     []any{1, 1, "a", "a"},
+			want:     "$1,$1,$2,$2",
+			wantArgs: []any{1, "a"},
+		},
+		{
+			name:     "by value unhashable",
+			dedupe:   sqlf.DedupeByValue,
+			args:     []any{bytes, bytes, slice, map[string]int{}, wrapper{slice}, wrapper{slice}},
+			want:     "$1,$2,$3,$4,$5,$6",
+			wantArgs: []any{bytes, bytes, slice, map[string]int{}, wrapper{slice}, wrapper{slice}},
+		},
+		{
+			name:     "off",
+			dedupe:   sqlf.DedupeOff,
+			args:     []any{1, 1},
+			want:     "$1,$2",
+			wantArgs: []any{1, 1},
+		},
+		{
+			name:     "by pointer",
+			dedupe:   sqlf.DedupeByPointer,
+			args:     []any{bytes, bytes, bytes[:1], ptr, ptr, 1, 1},
+			want:     "$1,$1,$2,$3,$3,$4,$5",
+			wantArgs: []any{bytes, bytes[:1], ptr, 1, 1},
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := sqlf.NewContext(syntax.Dollar, sqlf.WithDedupe(tc.dedupe))
+			got, err := sqlf.F("#join('#arg', ',')").WithArgs(tc.args...).BuildFragment(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+			if !reflect.DeepEqual(ctx.Args(), tc.wantArgs) {
+				t.Errorf("got %v, want %v", ctx.Args(), tc.wantArgs)
+			}
+		})
+	}
+}
+
+type namedArgStore struct {
+	args []any
+}
+
+func (s *namedArgStore) Args() []any {
+	return s.args
+}
+
+func (s *namedArgStore) CommitArg(arg any) string {
+	s.args = append(s.args, arg)
+	return "@p" + string(rune('0'+len(s.args)))
+}
+
+func TestWithArgStore(t *testing.T) {
+	t.Parallel()
+	ctx := sqlf.NewContext(syntax.Dollar, sqlf.WithArgStore(&namedArgStore{}))
+	got, err := sqlf.Fa("a = $1 AND b = $2", 1, 2).BuildFragment(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "a = @p1 AND b = @p2"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
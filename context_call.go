@@ -0,0 +1,177 @@
+package sqlf
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/qjebbs/go-sqlf/v2/syntax"
+)
+
+// builtCall is the built result of the condition of #if, which is
+// reused by the same calls in the branches, so that a fragment tested
+// by #if is built only once, rather than once for each level of the
+// nested conditions.
+type builtCall struct {
+	fc    *FragmentContext
+	calls map[*syntax.FuncCallExpr]bool // the calls in the branch same as the condition
+	texts []string                      // the built text split by the recorded args
+	args  []any                         // the recorded args
+}
+
+// newBuiltCall returns the built call of the condition for the calls
+// in the branch, or nil if there is no such call, or the built text
+// cannot be split by the recorded args.
+func newBuiltCall(fc *FragmentContext, cond *syntax.FuncCallExpr, branch []syntax.Expr, text string, store *recordArgStore) *builtCall {
+	calls := make(map[*syntax.FuncCallExpr]bool)
+	collectSameCalls(calls, cond, branch)
+	if len(calls) == 0 {
+		return nil
+	}
+	texts, ok := store.split(text)
+	if !ok {
+		return nil
+	}
+	return &builtCall{
+		fc:    fc,
+		calls: calls,
+		texts: texts,
+		args:  store.args,
+	}
+}
+
+// contextWithBuiltCall returns a new context with the built call,
+// which is visible to the builds of the same fragment.
+func contextWithBuiltCall(ctx *Context, call *builtCall) *Context {
+	c, _ := contextWith(ctx, func(c *Context) error {
+		c.call = call
+		return nil
+	})
+	return c
+}
+
+// buildCall builds the function call, the result of a same call
+// built by an enclosing #if condition is reused if any.
+func buildCall(ctx *Context, expr *syntax.FuncCallExpr) (string, error) {
+	fc, _ := ctx.Fragment()
+	call, ok := contextValue(ctx, func(c *Context) (*builtCall, bool) {
+		if c.call == nil || c.call.fc != fc {
+			return nil, false
+		}
+		return c.call, c.call.calls[expr]
+	})
+	if ok {
+		return call.replay(ctx), nil
+	}
+	return evalFunction(ctx, expr.Name, expr.Args)
+}
+
+// replay commits the recorded args to ctx, and returns the built text
+// with the bindvars of them.
+func (c *builtCall) replay(ctx *Context) string {
+	if len(c.args) == 0 {
+		return c.texts[0]
+	}
+	b := new(strings.Builder)
+	b.WriteString(c.texts[0])
+	for i, arg := range c.args {
+		b.WriteString(ctx.CommitArg(arg))
+		b.WriteString(c.texts[i+1])
+	}
+	return b.String()
+}
+
+// collectSameCalls collects the calls in exprs, including the nested
+// ones, which are the same as cond.
+func collectSameCalls(r map[*syntax.FuncCallExpr]bool, cond *syntax.FuncCallExpr, exprs []syntax.Expr) {
+	for _, e := range exprs {
+		switch e := e.(type) {
+		case *syntax.FuncCallExpr:
+			if sameCall(e, cond) {
+				r[e] = true
+			}
+			for _, arg := range e.Args {
+				if arg, ok := arg.(syntax.Expr); ok {
+					collectSameCalls(r, cond, []syntax.Expr{arg})
+				}
+			}
+		case *syntax.IfExpr:
+			collectSameCalls(r, cond, []syntax.Expr{e.Cond})
+			collectSameCalls(r, cond, e.Then)
+			collectSameCalls(r, cond, e.Else)
+		}
+	}
+}
+
+// sameCall reports whether a and b call the same function with the
+// same args, e.g. #f1 and #f(1).
+func sameCall(a, b *syntax.FuncCallExpr) bool {
+	if a.Name != b.Name || len(a.Args) != len(b.Args) {
+		return false
+	}
+	for i, arg := range a.Args {
+		switch arg := arg.(type) {
+		case *syntax.FuncCallExpr:
+			other, ok := b.Args[i].(*syntax.FuncCallExpr)
+			if !ok || !sameCall(arg, other) {
+				return false
+			}
+		case *syntax.FuncExpr:
+			other, ok := b.Args[i].(*syntax.FuncExpr)
+			if !ok || arg.Name != other.Name {
+				return false
+			}
+		default:
+			if _, ok := b.Args[i].(syntax.Expr); ok || arg != b.Args[i] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// recordArgStore records the committed args, and returns marks as the
+// bindvars, by which the built text is split in the order of the args.
+type recordArgStore struct {
+	args []any
+}
+
+func (s *recordArgStore) Args() []any {
+	return s.args
+}
+
+func (s *recordArgStore) CommitArg(arg any) string {
+	s.args = append(s.args, arg)
+	return recordMark(len(s.args))
+}
+
+// split splits the text by the marks of the recorded args. It reports
+// false if any mark is missing, out of order, or written more than once,
+// e.g. the text contains a sequence like the marks.
+func (s *recordArgStore) split(text string) ([]string, bool) {
+	texts := make([]string, 0, len(s.args)+1)
+	rest := text
+	for i := range s.args {
+		mark := recordMark(i + 1)
+		j := strings.Index(rest, mark)
+		if j < 0 || strings.Count(text, mark) != 1 {
+			return nil, false
+		}
+		texts = append(texts, rest[:j])
+		rest = rest[j+len(mark):]
+	}
+	return append(texts, rest), true
+}
+
+// recordMark returns the mark of the n-th recorded arg.
+func recordMark(n int) string {
+	return "\x00" + strconv.Itoa(n) + "\x00"
+}
+
+// contextWithArgStore returns a new context committing the args to s.
+func contextWithArgStore(ctx *Context, s ArgStore) *Context {
+	c, _ := contextWith(ctx, func(c *Context) error {
+		c.argStore = s
+		return nil
+	})
+	return c
+}
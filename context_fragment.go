@@ -2,22 +2,20 @@ package sqlf
 
 import (
 	"errors"
-	"fmt"
-	"strings"
 )
 
-// fragment returns the fragment context.
+// Fragment returns the context of current building fragment.
 //
-// it's used usually in the implementation of a FragmentBuilder,
-// most users don't need to care about it.
-func (c *Context) fragment() (*FragmentContext, bool) {
+// It's used usually in the implementation of a FragmentBuilder or
+// a custom function, most users don't need to care about it.
+func (c *Context) Fragment() (*FragmentContext, bool) {
 	return contextValue(c, func(c *Context) (*FragmentContext, bool) {
 		return c.frag, c.frag != nil
 	})
 }
 
 func (c *Context) mustFragment() (*FragmentContext, error) {
-	fc, ok := c.fragment()
+	fc, ok := c.Fragment()
 	if !ok {
 		return nil, errors.New("no fragment context")
 	}
@@ -38,6 +36,10 @@ type FragmentContext struct {
 	Fragment  *Fragment
 	Args      Properties
 	Fragments Properties
+
+	// usageUnknown reports whether a skipped #if branch calls a custom
+	// function, of which the referenced args and fragments are unknown.
+	usageUnknown bool
 }
 
 func newFragmentContext(f *Fragment) *FragmentContext {
@@ -53,22 +55,15 @@ func newFragmentContext(f *Fragment) *FragmentContext {
 
 // checkUsage checks if all args and properties are used.
 func (c *FragmentContext) checkUsage() error {
-	if c == nil {
+	if c == nil || c.usageUnknown {
 		return nil
 	}
-	msgs := make([]string, 0, 5)
-	if err := c.Args.checkUsage(); err != nil {
-		msgs = append(msgs, fmt.Sprintf(
-			"args %s", err.Error(),
-		))
-	}
-	if err := c.Fragments.checkUsage(); err != nil {
-		msgs = append(msgs, fmt.Sprintf(
-			"properties %s", err.Error(),
-		))
+	args, fragments := c.Args.unused(), c.Fragments.unused()
+	if len(args) == 0 && len(fragments) == 0 {
+		return nil
 	}
-	if len(msgs) > 0 {
-		return errors.New(strings.Join(msgs, "; "))
+	return &UnusedError{
+		Args:      args,
+		Fragments: fragments,
 	}
-	return nil
 }
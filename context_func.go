@@ -1,11 +1,45 @@
 package sqlf
 
+// FuncEnv is an immutable set of preprocessing functions, which are
+// reflected and validated once by NewFuncEnv, and can be shared by any
+// number of contexts, including the ones of concurrent builds.
+//
+//	env, err := sqlf.NewFuncEnv(funcs) // once, e.g. at startup
+//	// per build
+//	ctx := sqlf.ContextWithFuncEnv(sqlf.NewContext(syntax.Dollar), env)
+type FuncEnv struct {
+	funcs map[string]*funcInfo
+}
+
+// NewFuncEnv returns a new FuncEnv of the funcs.
+func NewFuncEnv(funcs FuncMap) (*FuncEnv, error) {
+	m, err := createValueFuncs(funcs)
+	if err != nil {
+		return nil, err
+	}
+	return &FuncEnv{funcs: m}, nil
+}
+
+// ContextWithFuncEnv returns a new context with the functions of env
+// added, which is cheap since env is shared rather than copied.
+func ContextWithFuncEnv(c *Context, env *FuncEnv) *Context {
+	ctx, _ := contextWith(c, func(c *Context) error {
+		c.funcs = env.funcs
+		return nil
+	})
+	return ctx
+}
+
 // ContextWithFuncs returns a new context with the preprocessing functions added.
+//
+// The funcs are reflected and validated on every call, use NewFuncEnv
+// and ContextWithFuncEnv to do it once for many builds.
 func ContextWithFuncs(c *Context, funcs FuncMap) (*Context, error) {
-	return contextWith(c, func(c *Context) error {
-		c.funcs = make(map[string]*funcInfo)
-		return addValueFuncs(c.funcs, funcs)
-	})
+	env, err := NewFuncEnv(funcs)
+	if err != nil {
+		return nil, err
+	}
+	return ContextWithFuncEnv(c, env), nil
 }
 
 func (c *Context) fn(name string) (*funcInfo, bool) {
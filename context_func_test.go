@@ -0,0 +1,98 @@
+package sqlf_test
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/qjebbs/go-sqlf/v2"
+	"github.com/qjebbs/go-sqlf/v2/syntax"
+)
+
+func TestFuncEnvConcurrent(t *testing.T) {
+	t.Parallel()
+	env, err := sqlf.NewFuncEnv(sqlf.FuncMap{
+		"col": func(i int) (string, error) {
+			if i < 1 || i > 3 {
+				return "", sqlf.ErrInvalidIndex
+			}
+			return "c" + strconv.Itoa(i), nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a context shared by the goroutines for validation
+	shared := sqlf.ContextWithFuncEnv(sqlf.NewContext(syntax.Dollar), env)
+	var wg sync.WaitGroup
+	errs := make(chan error, 16)
+	for i := 0; i < cap(errs); i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := sqlf.ValidateContext(shared, sqlf.F("#join('#col', ', ')")); err != nil {
+				errs <- err
+				return
+			}
+			ctx := sqlf.ContextWithFuncEnv(sqlf.NewContext(syntax.Dollar), env)
+			got, err := sqlf.Fa("SELECT #join('#col', ', ') FROM t WHERE id = $1", i).BuildFragment(ctx)
+			if err != nil {
+				errs <- err
+				return
+			}
+			if want := "SELECT c1, c2, c3 FROM t WHERE id = $1"; got != want {
+				errs <- fmt.Errorf("got %q, want %q", got, want)
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestNewFuncEnvError(t *testing.T) {
+	t.Parallel()
+	if _, err := sqlf.NewFuncEnv(sqlf.FuncMap{"bad": func(ch chan int) string { return "" }}); err == nil {
+		t.Error("want error of unsupported argument type, got nil")
+	}
+}
+
+func TestFuncEnvLazyJoinCheck(t *testing.T) {
+	t.Parallel()
+	calls := 0
+	env, err := sqlf.NewFuncEnv(sqlf.FuncMap{
+		"col": func(i int) (string, error) {
+			calls++
+			if i < 1 || i > 2 {
+				return "", sqlf.ErrInvalidIndex
+			}
+			return "c" + strconv.Itoa(i), nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 0 {
+		t.Fatalf("the function is called %d times on registration, want 0", calls)
+	}
+	ctx := sqlf.ContextWithFuncEnv(sqlf.NewContext(syntax.Dollar), env)
+	if _, err := sqlf.F("#col1").BuildFragment(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 1 {
+		t.Fatalf("the function is called %d times without #join, want 1", calls)
+	}
+	for i := 0; i < 2; i++ {
+		ctx := sqlf.ContextWithFuncEnv(sqlf.NewContext(syntax.Dollar), env)
+		if _, err := sqlf.F("#join('#col', ', ')").BuildFragment(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// tested once with index 0, then called with 1, 2 and 3 by each #join
+	if want := 1 + 1 + 2*3; calls != want {
+		t.Errorf("the function is called %d times, want %d", calls, want)
+	}
+}
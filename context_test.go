@@ -21,7 +21,7 @@ func TestContextWithFragment(t *testing.T) {
 		"parents": func(ctx *Context) (string, error) {
 			parents := make([]string, 0)
 			for c := ctx.parent; c != nil; c = c.parent {
-				fc, ok := c.fragment()
+				fc, ok := c.Fragment()
 				if !ok {
 					continue
 				}
@@ -42,3 +42,42 @@ func TestContextWithFragment(t *testing.T) {
 		t.Fatalf("got %v, want %v", got, want)
 	}
 }
+
+func TestContextWithValue(t *testing.T) {
+	t.Parallel()
+	type key struct{}
+	type other struct{}
+	ctx := NewContext(syntax.Dollar)
+	if v := ctx.Value(key{}); v != nil {
+		t.Fatalf("want nil, got %v", v)
+	}
+	outer := ContextWithValue(ctx, key{}, 1)
+	inner := ContextWithValue(ContextWithValue(outer, other{}, "x"), key{}, 2)
+	if v := outer.Value(key{}); v != 1 {
+		t.Errorf("outer: want 1, got %v", v)
+	}
+	if v := inner.Value(key{}); v != 2 {
+		t.Errorf("inner: want 2, got %v", v)
+	}
+	if v := inner.Value(other{}); v != "x" {
+		t.Errorf("other: want x, got %v", v)
+	}
+	// the args are shared with the parent
+	if got := inner.CommitArg(1); got != "$1" || len(ctx.Args()) != 1 {
+		t.Errorf("want $1 committed to the parent, got %s, %v", got, ctx.Args())
+	}
+	// a key of other type is not compared with the values
+	if v := inner.Value([]int{1}); v != nil {
+		t.Errorf("slice: want nil, got %v", v)
+	}
+	for _, key := range []any{nil, []int{1}} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("want panic for key %v", key)
+				}
+			}()
+			ContextWithValue(ctx, key, 1)
+		}()
+	}
+}
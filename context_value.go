@@ -0,0 +1,46 @@
+package sqlf
+
+import "reflect"
+
+// ContextWithValue returns a new context carrying the value of the key,
+// which is visible to the builders built with the new context and the
+// ones derived from it, like context.WithValue.
+//
+// It's used usually in the implementation of a FragmentBuilder to pass
+// information to the nested builders, e.g. the tables of the outer query
+// to a correlated subquery. The key must be comparable, and should be of
+// an unexported type to avoid collisions.
+//
+// The values are the state of the building shared between the builders.
+// The request-scoped values of the caller, e.g. the tracing spans and
+// deadlines, are carried by the context.Context set by WithGoContext
+// instead, see GoContext.
+func ContextWithValue(c *Context, key, val any) *Context {
+	if key == nil {
+		panic("sqlf: nil key")
+	}
+	if !reflect.TypeOf(key).Comparable() {
+		panic("sqlf: key is not comparable")
+	}
+	ctx, _ := contextWith(c, func(c *Context) error {
+		c.values = &contextEntry{key: key, val: val}
+		return nil
+	})
+	return ctx
+}
+
+// Value returns the value of the key set by the nearest ContextWithValue,
+// or nil if there is none.
+func (c *Context) Value(key any) any {
+	v, _ := contextValue(c, func(c *Context) (any, bool) {
+		if c.values == nil || c.values.key != key {
+			return nil, false
+		}
+		return c.values.val, true
+	})
+	return v
+}
+
+type contextEntry struct {
+	key, val any
+}
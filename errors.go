@@ -1,9 +1,230 @@
 package sqlf
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/qjebbs/go-sqlf/v2/syntax"
+)
 
 var (
 	// ErrInvalidIndex is returned when the reference index is invalid.
 	// It's a required behaviour for a custom #func to be compatible with #join.
 	ErrInvalidIndex = errors.New("invalid index")
+	// ErrParamValue is returned when the query shape depends on the value
+	// of a Param, e.g. #if(arg1) of a Param, which is unknown until the
+	// Template is executed. Functions deciding on arg values should report
+	// it, so that Compile rejects the builder.
+	ErrParamValue = errors.New("the value of a Param is unknown at compile time")
+	// ErrEmptySlice is returned when an empty slice is expanded without
+	// the expression set by WithEmptySliceExpr.
+	ErrEmptySlice = errors.New("empty slice to expand, see WithEmptySliceExpr")
 )
+
+// BuildError is the error of building a fragment, which carries
+// where the failure happens in the fragment tree.
+//
+// The cause is one of the typed errors below, which can be
+// extracted with errors.As:
+//
+//   - *syntax.Error: syntax error of the Raw
+//   - *UnknownFuncError: call of an undefined function
+//   - *InvalidIndexError: reference to a nonexistent arg or fragment
+//   - *UnusedError: args or fragments never referenced
+//   - *BindVarError: bindvar referencing a nonexistent arg
+//
+// Use %+v to print it with a caret-annotated excerpt of the Raw.
+type BuildError struct {
+	Raw  string       // Raw of the fragment where the failure happens.
+	Pos  syntax.Pos   // Pos of the failure in Raw, unknown for the errors of the whole fragment.
+	Path []ErrorFrame // Path of the ancestor fragments, from the root to the parent.
+	Err  error        // Err is the cause.
+}
+
+// ErrorFrame is an ancestor fragment in the path of a BuildError.
+type ErrorFrame struct {
+	Raw string     // Raw of the ancestor fragment.
+	Pos syntax.Pos // Pos in Raw, where the child is referenced.
+}
+
+func (e *BuildError) Error() string {
+	return fmt.Sprintf("build %s: %s", location(e.Raw, e.Pos), e.Err)
+}
+
+// Unwrap returns the cause.
+func (e *BuildError) Unwrap() error {
+	return e.Err
+}
+
+// Excerpt returns the line of Raw where the failure happens,
+// with a caret pointing to the position.
+func (e *BuildError) Excerpt() string {
+	return excerpt(e.Raw, e.Pos)
+}
+
+// Format implements fmt.Formatter, the verb %+v prints the error
+// with the excerpt and the path.
+func (e *BuildError) Format(s fmt.State, verb rune) {
+	if verb != 'v' || !s.Flag('+') {
+		io.WriteString(s, e.Error())
+		return
+	}
+	io.WriteString(s, e.Error())
+	if x := e.Excerpt(); x != "" {
+		io.WriteString(s, "\n")
+		io.WriteString(s, indent(x, "\t"))
+	}
+	for i := len(e.Path) - 1; i >= 0; i-- {
+		frame := e.Path[i]
+		fmt.Fprintf(s, "\nin %s", location(frame.Raw, frame.Pos))
+		if x := excerpt(frame.Raw, frame.Pos); x != "" {
+			io.WriteString(s, "\n")
+			io.WriteString(s, indent(x, "\t"))
+		}
+	}
+}
+
+func location(raw string, pos syntax.Pos) string {
+	if pos.Line() == 0 {
+		return fmt.Sprintf("'%s'", raw)
+	}
+	return fmt.Sprintf("'%s' (%s)", raw, pos)
+}
+
+func excerpt(raw string, pos syntax.Pos) string {
+	if pos.Line() == 0 || pos.Col() == 0 {
+		return ""
+	}
+	lines := strings.Split(raw, "\n")
+	if int(pos.Line()) > len(lines) {
+		return ""
+	}
+	line := []rune(lines[pos.Line()-1])
+	col := int(pos.Col())
+	if col > len(line)+1 {
+		return ""
+	}
+	caret := new(strings.Builder)
+	for _, r := range line[:col-1] {
+		// keep tabs to align the caret
+		if r == '\t' {
+			caret.WriteRune('\t')
+		} else {
+			caret.WriteRune(' ')
+		}
+	}
+	caret.WriteRune('^')
+	return string(line) + "\n" + caret.String()
+}
+
+func indent(s, prefix string) string {
+	return prefix + strings.ReplaceAll(s, "\n", "\n"+prefix)
+}
+
+// UnknownFuncError is returned when calling an undefined function.
+type UnknownFuncError struct {
+	Name string
+}
+
+func (e *UnknownFuncError) Error() string {
+	return fmt.Sprintf("unknown function #%s", e.Name)
+}
+
+// InvalidIndexError is returned when referencing a nonexistent
+// arg or fragment, it matches ErrInvalidIndex with errors.Is.
+type InvalidIndexError struct {
+	Index int
+	Name  string // Name is set instead of Index for a reference by name.
+}
+
+func (e *InvalidIndexError) Error() string {
+	if e.Name != "" {
+		return fmt.Sprintf("unknown name '%s'", e.Name)
+	}
+	return fmt.Sprintf("%s: %d", ErrInvalidIndex, e.Index)
+}
+
+// Is reports whether target is ErrInvalidIndex.
+func (e *InvalidIndexError) Is(target error) bool {
+	return target == ErrInvalidIndex
+}
+
+// UnusedError is returned when args or fragments are never referenced.
+type UnusedError struct {
+	Args      []int // indexes of the unused args, starting from 1
+	Fragments []int // indexes of the unused fragments, starting from 1
+}
+
+func (e *UnusedError) Error() string {
+	msgs := make([]string, 0, 2)
+	if len(e.Args) > 0 {
+		msgs = append(msgs, "args "+formatIndexes(e.Args)+" unused")
+	}
+	if len(e.Fragments) > 0 {
+		msgs = append(msgs, "fragments "+formatIndexes(e.Fragments)+" unused")
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func formatIndexes(indexes []int) string {
+	s := make([]string, 0, len(indexes))
+	for _, i := range indexes {
+		s = append(s, fmt.Sprintf("#%d", i))
+	}
+	return strings.Join(s, ", ")
+}
+
+// BindVarError is returned when a bindvar references a nonexistent arg.
+type BindVarError struct {
+	Index int // index of the bindvar
+	NArgs int // number of the args
+}
+
+func (e *BindVarError) Error() string {
+	return fmt.Sprintf("invalid bind var index %d, the fragment has %d args", e.Index, e.NArgs)
+}
+
+// posError attaches the position of the expression to the error.
+type posError struct {
+	pos syntax.Pos
+	err error
+}
+
+func (e *posError) Error() string {
+	return e.err.Error()
+}
+
+func (e *posError) Unwrap() error {
+	return e.err
+}
+
+// newBuildError creates a *BuildError of the fragment from err.
+//
+// If err is caused by a descendant fragment, the fragment is
+// added to the path of its *BuildError, instead of wrapping it.
+func newBuildError(f *Fragment, err error) error {
+	var pos syntax.Pos
+	var pe *posError
+	if errors.As(err, &pe) {
+		pos = pe.pos
+	}
+	var be *BuildError
+	if errors.As(err, &be) {
+		be.Path = append([]ErrorFrame{{Raw: f.Raw, Pos: pos}}, be.Path...)
+		return be
+	}
+	if pe != nil {
+		err = pe.err
+	}
+	var se *syntax.Error
+	if errors.As(err, &se) {
+		pos = se.Pos
+	}
+	return &BuildError{
+		Raw: f.Raw,
+		Pos: pos,
+		Err: err,
+	}
+}
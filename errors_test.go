@@ -0,0 +1,114 @@
+package sqlf_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/qjebbs/go-sqlf/v2"
+	"github.com/qjebbs/go-sqlf/v2/syntax"
+)
+
+func TestBuildError(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		name     string
+		fragment *sqlf.Fragment
+		wantRaw  string
+		wantPos  string
+		wantPath []string
+		wantErr  any
+	}{
+		{
+			name:     "syntax error",
+			fragment: sqlf.Ff("a #f1", sqlf.Fa("b = $1 OR b = ?", 1)),
+			wantRaw:  "b = $1 OR b = ?",
+			wantPos:  "1:15",
+			wantPath: []string{"a #f1"},
+			wantErr:  new(*syntax.Error),
+		},
+		{
+			name: "unknown function",
+			fragment: sqlf.Ff("L1 #f1", sqlf.Ff(
+				"L2\n #f1", sqlf.F("L3 #foo()"),
+			)),
+			wantRaw:  "L3 #foo()",
+			wantPos:  "1:4",
+			wantPath: []string{"L1 #f1", "L2\n #f1"},
+			wantErr:  new(*sqlf.UnknownFuncError),
+		},
+		{
+			name:     "invalid index",
+			fragment: sqlf.Ff("#f1, #f2", sqlf.F("a")),
+			wantRaw:  "#f1, #f2",
+			wantPos:  "1:6",
+			wantErr:  new(*sqlf.InvalidIndexError),
+		},
+		{
+			name:     "unused",
+			fragment: sqlf.Ff("#f1", sqlf.Fa("a", 1)),
+			wantRaw:  "a",
+			wantPos:  "<unknown position>",
+			wantPath: []string{"#f1"},
+			wantErr:  new(*sqlf.UnusedError),
+		},
+		{
+			name:     "bindvar",
+			fragment: sqlf.Fa("a = $1 AND b = $2", 1),
+			wantRaw:  "a = $1 AND b = $2",
+			wantPos:  "1:16",
+			wantErr:  new(*sqlf.BindVarError),
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			_, _, err := tc.fragment.BuildQuery(syntax.Dollar)
+			if err == nil {
+				t.Fatal("want error, got nil")
+			}
+			var be *sqlf.BuildError
+			if !errors.As(err, &be) {
+				t.Fatalf("want *sqlf.BuildError, got %T", err)
+			}
+			if be.Raw != tc.wantRaw {
+				t.Errorf("got raw %q, want %q", be.Raw, tc.wantRaw)
+			}
+			if be.Pos.String() != tc.wantPos {
+				t.Errorf("got pos %s, want %s", be.Pos, tc.wantPos)
+			}
+			if len(be.Path) != len(tc.wantPath) {
+				t.Fatalf("got path %v, want %v", be.Path, tc.wantPath)
+			}
+			for i, frame := range be.Path {
+				if frame.Raw != tc.wantPath[i] {
+					t.Errorf("got path %v, want %v", be.Path, tc.wantPath)
+				}
+			}
+			if !errors.As(err, tc.wantErr) {
+				t.Errorf("want %T, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func ExampleBuildError() {
+	_, _, err := sqlf.Ff(
+		"SELECT * FROM foo WHERE #f1",
+		sqlf.Fa("id = $1 AND #foo(1)", 1),
+	).BuildQuery(syntax.Dollar)
+	fmt.Printf("%+v\n", err)
+
+	var ue *sqlf.UnknownFuncError
+	if errors.As(err, &ue) {
+		fmt.Println(ue.Name)
+	}
+	// Output:
+	// build 'id = $1 AND #foo(1)' (1:13): unknown function #foo
+	// 	id = $1 AND #foo(1)
+	// 	            ^
+	// in 'SELECT * FROM foo WHERE #f1' (1:25)
+	// 	SELECT * FROM foo WHERE #f1
+	// 	                        ^
+	// foo
+}
@@ -0,0 +1,91 @@
+package sqlf
+
+import (
+	"reflect"
+	"strings"
+)
+
+// SliceExpansion is the policy of expanding slice args.
+//
+// When enabled, a slice or array arg (except []byte and driver.Valuer)
+// is expanded into comma-separated bindvars wherever it is referenced,
+// by a bindvar or #arg, for example:
+//
+//	sqlf.Fa("id IN ($1)", []int{1, 2, 3}) // id IN ($1, $2, $3)
+//
+// An empty slice builds the expression set by WithEmptySliceExpr, or
+// fails the building with ErrEmptySlice if it's not set, rather than
+// building the invalid `id IN ()`.
+type SliceExpansion int
+
+// Slice expansion policies.
+const (
+	// ExpansionInherit inherits the policy of the context,
+	// which is ExpansionOff if not set.
+	ExpansionInherit SliceExpansion = iota
+	// ExpansionOn expands slice args.
+	ExpansionOn
+	// ExpansionOff commits slice args as single values.
+	ExpansionOff
+)
+
+// WithSliceExpansion sets the default SliceExpansion for all fragments
+// built with the context, which is overridden by Fragment.Expansion.
+func WithSliceExpansion(e SliceExpansion) ContextOption {
+	return func(o *contextOptions) {
+		o.expansion = e
+	}
+}
+
+// WithEmptySliceExpr sets the expression built for empty slices when
+// SliceExpansion is enabled, without which the building of an empty
+// slice fails with ErrEmptySlice.
+//
+// Choose it with care, since no expression suits both IN and NOT IN,
+// e.g. with NULL, `id IN (NULL)` matches no rows as expected, but
+// `id NOT IN (NULL)` also matches no rows, rather than all of them.
+func WithEmptySliceExpr(expr string) ContextOption {
+	return func(o *contextOptions) {
+		o.emptySlice = expr
+	}
+}
+
+// WithExpansion sets the SliceExpansion of f.
+func (f *Fragment) WithExpansion(e SliceExpansion) *Fragment {
+	f.Expansion = e
+	return f
+}
+
+// expandSlices reports whether slice args of current fragment should be expanded.
+func (c *Context) expandSlices() bool {
+	if fc, ok := c.Fragment(); ok && fc.Fragment != nil && fc.Fragment.Expansion != ExpansionInherit {
+		return fc.Fragment.Expansion == ExpansionOn
+	}
+	return c.root().expansion == ExpansionOn
+}
+
+// EmptySliceExpr returns the expression for empty slices set by
+// WithEmptySliceExpr, or ErrEmptySlice if it's not set.
+//
+// It's used usually in the implementation of a function expanding
+// slices, e.g. #in of the funcs package.
+func (c *Context) EmptySliceExpr() (string, error) {
+	if expr := c.root().emptySlice; expr != "" {
+		return expr, nil
+	}
+	return "", ErrEmptySlice
+}
+
+// commitSlice commits the items of the slice v, and returns the bindvars
+// separated by comma.
+func (c *Context) commitSlice(v any) (string, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Len() == 0 {
+		return c.EmptySliceExpr()
+	}
+	items := make([]string, 0, rv.Len())
+	for i := 0; i < rv.Len(); i++ {
+		items = append(items, c.CommitArg(rv.Index(i).Interface()))
+	}
+	return strings.Join(items, ", "), nil
+}
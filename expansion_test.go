@@ -0,0 +1,125 @@
+package sqlf_test
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/qjebbs/go-sqlf/v2"
+	"github.com/qjebbs/go-sqlf/v2/syntax"
+)
+
+func TestSliceExpansion(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		name     string
+		fragment *sqlf.Fragment
+		style    syntax.BindVarStyle
+		options  []sqlf.ContextOption
+		want     string
+		wantArgs []any
+		wantErr  error
+	}{
+		{
+			name:     "off by default",
+			fragment: sqlf.Fa("id IN ($1)", []int{1, 2}),
+			want:     "id IN ($1)",
+			wantArgs: []any{[]int{1, 2}},
+		},
+		{
+			name:     "context",
+			fragment: sqlf.Fa("id IN ($1) AND type = $2", []int64{1, 2}, 3),
+			options:  []sqlf.ContextOption{sqlf.WithSliceExpansion(sqlf.ExpansionOn)},
+			want:     "id IN ($1, $2) AND type = $3",
+			wantArgs: []any{int64(1), int64(2), 3},
+		},
+		{
+			name:     "question",
+			fragment: sqlf.Fa("id IN (?) AND type = ?", [2]string{"a", "b"}, "c"),
+			style:    syntax.Question,
+			options:  []sqlf.ContextOption{sqlf.WithSliceExpansion(sqlf.ExpansionOn)},
+			want:     "id IN (?, ?) AND type = ?",
+			wantArgs: []any{"a", "b", "c"},
+		},
+		{
+			name:     "fragment",
+			fragment: sqlf.Fa("id IN (#arg1)", []int{1, 2}).WithExpansion(sqlf.ExpansionOn),
+			want:     "id IN ($1, $2)",
+			wantArgs: []any{1, 2},
+		},
+		{
+			name:     "fragment overrides context",
+			fragment: sqlf.Fa("id IN ($1)", []int{1, 2}).WithExpansion(sqlf.ExpansionOff),
+			options:  []sqlf.ContextOption{sqlf.WithSliceExpansion(sqlf.ExpansionOn)},
+			want:     "id IN ($1)",
+			wantArgs: []any{[]int{1, 2}},
+		},
+		{
+			name:     "bytes not expanded",
+			fragment: sqlf.Fa("data = $1", []byte("ab")),
+			options:  []sqlf.ContextOption{sqlf.WithSliceExpansion(sqlf.ExpansionOn)},
+			want:     "data = $1",
+			wantArgs: []any{[]byte("ab")},
+		},
+		{
+			name:     "empty",
+			fragment: sqlf.Fa("id IN ($1)", []int{}),
+			options:  []sqlf.ContextOption{sqlf.WithSliceExpansion(sqlf.ExpansionOn)},
+			wantErr:  sqlf.ErrEmptySlice,
+		},
+		{
+			name:     "empty expr",
+			fragment: sqlf.Fa("id IN ($1)", []int{}),
+			options: []sqlf.ContextOption{
+				sqlf.WithSliceExpansion(sqlf.ExpansionOn),
+				sqlf.WithEmptySliceExpr("SELECT 1 WHERE FALSE"),
+			},
+			want: "id IN (SELECT 1 WHERE FALSE)",
+		},
+		{
+			name: "inherit in child",
+			fragment: sqlf.Ff("WHERE #f1",
+				sqlf.Fa("id IN ($1)", []int{1, 2}),
+			).WithExpansion(sqlf.ExpansionOn),
+			want:     "WHERE id IN ($1)",
+			wantArgs: []any{[]int{1, 2}},
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := sqlf.NewContext(tc.style, tc.options...)
+			got, err := tc.fragment.BuildFragment(ctx)
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Fatalf("want error %v, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+			if args := ctx.Args(); !reflect.DeepEqual(args, tc.wantArgs) {
+				t.Errorf("got args %v, want %v", args, tc.wantArgs)
+			}
+		})
+	}
+}
+
+func ExampleWithSliceExpansion() {
+	ctx := sqlf.NewContext(syntax.Dollar, sqlf.WithSliceExpansion(sqlf.ExpansionOn))
+	query, err := sqlf.Fa("SELECT * FROM foo WHERE id IN ($1)", []int64{1, 2, 3}).BuildFragment(ctx)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println(query)
+	fmt.Println(ctx.Args())
+	// Output:
+	// SELECT * FROM foo WHERE id IN ($1, $2, $3)
+	// [1 2 3]
+}
@@ -8,6 +8,11 @@ type Fragment struct {
 	Fragments []FragmentBuilder // Fragments can be referenced by the Raw, for example: #f1, #fragment1
 	Prefix    string            // Prefix is added before the fragment only when the fragment is built not empty.
 	Suffix    string            // Suffix is added after the fragment only when the fragment is built not empty.
+	Usage     UsagePolicy       // Usage is the policy for unused Args and Fragments, it inherits the one of the context if not set.
+	Expansion SliceExpansion    // Expansion is the policy for slice Args, it inherits the one of the context if not set.
+
+	ArgNames      []string // ArgNames names the Args by position, so that they can be referenced like #arg('name').
+	FragmentNames []string // FragmentNames names the Fragments by position, so that they can be referenced like #f('name').
 }
 
 // WithPrefix sets the prefix which is added before the fragment only when the f is built not empty.
@@ -22,15 +27,18 @@ func (f *Fragment) WithSuffix(suffix string) *Fragment {
 	return f
 }
 
-// WithArgs sets the args of f.
+// WithArgs sets the args of f, and clears the ArgNames of the old ones.
 func (f *Fragment) WithArgs(args ...any) *Fragment {
 	f.Args = args
+	f.ArgNames = nil
 	return f
 }
 
-// WithFragments sets the fragments of f.
+// WithFragments sets the fragments of f, and clears the FragmentNames
+// of the old ones.
 func (f *Fragment) WithFragments(fragments ...FragmentBuilder) *Fragment {
 	f.Fragments = fragments
+	f.FragmentNames = nil
 	return f
 }
 
@@ -45,3 +53,28 @@ func (f *Fragment) AppendFragments(fragments ...FragmentBuilder) *Fragment {
 	f.Fragments = append(f.Fragments, fragments...)
 	return f
 }
+
+// AppendNamedArg appends an arg named name to f,
+// which can be referenced by #arg('name') or #arg(name).
+func (f *Fragment) AppendNamedArg(name string, arg any) *Fragment {
+	f.ArgNames = appendName(f.ArgNames, len(f.Args), name)
+	f.Args = append(f.Args, arg)
+	return f
+}
+
+// AppendNamedFragment appends a fragment named name to f,
+// which can be referenced by #f('name') or #f(name).
+func (f *Fragment) AppendNamedFragment(name string, fragment FragmentBuilder) *Fragment {
+	f.FragmentNames = appendName(f.FragmentNames, len(f.Fragments), name)
+	f.Fragments = append(f.Fragments, fragment)
+	return f
+}
+
+// appendName appends name to names as the name of the item at index n,
+// padding the unnamed items before it.
+func appendName(names []string, n int, name string) []string {
+	for len(names) < n {
+		names = append(names, "")
+	}
+	return append(names[:n], name)
+}
@@ -181,3 +181,20 @@ func TestBuildFragment(t *testing.T) {
 		})
 	}
 }
+
+func TestParseOptions(t *testing.T) {
+	t.Parallel()
+	raw := "SELECT 'it\\'s #f1' = $1 -- $2 #f1"
+	fragment := sqlf.Fa(raw, 1)
+	ctx := sqlf.NewContext(syntax.Dollar, sqlf.WithParseOptions(syntax.WithBackslashEscapes()))
+	got, err := fragment.BuildFragment(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != raw {
+		t.Errorf("got %q, want %q", got, raw)
+	}
+	if _, err := fragment.BuildFragment(sqlf.NewContext(syntax.Dollar)); err == nil {
+		t.Error("want error without backslash escapes, got nil")
+	}
+}
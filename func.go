@@ -4,16 +4,21 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"sync"
 	"unicode"
 
 	"github.com/qjebbs/go-sqlf/v2/syntax"
 )
 
 var (
-	errorType        = reflect.TypeOf((*error)(nil)).Elem()
-	stringType       = reflect.TypeOf((*string)(nil)).Elem()
-	fmtStringerType  = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
-	reflectValueType = reflect.TypeOf((*reflect.Value)(nil)).Elem()
+	errorType           = reflect.TypeOf((*error)(nil)).Elem()
+	stringType          = reflect.TypeOf((*string)(nil)).Elem()
+	boolType            = reflect.TypeOf((*bool)(nil)).Elem()
+	fmtStringerType     = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+	reflectValueType    = reflect.TypeOf((*reflect.Value)(nil)).Elem()
+	anyType             = reflect.TypeOf((*any)(nil)).Elem()
+	fragmentBuilderType = reflect.TypeOf((*FragmentBuilder)(nil)).Elem()
+	funcValueType       = reflect.TypeOf(FuncValue{})
 
 	contextPointerType = reflect.TypeOf((*Context)(nil))
 )
@@ -27,11 +32,16 @@ var (
 //	func(/* args... */) (string, error)
 //	func(/* args... */) string
 //	func(/* args... */)
+//	func(/* args... */) (bool, error) // condition only, see below
+//	func(/* args... */) bool          // condition only, see below
 //
 // Allowed argument types:
 //   - number types: int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64,float32, float64
 //   - string
 //   - bool
+//   - any: receives the number (float64, or int in #join), string, bool or nil as is
+//   - sqlf.FragmentBuilder: receives a nested function call, e.g. #f1 in #my_func(#f1), which is evaluated when built
+//   - sqlf.FuncValue: receives a function value, e.g. #arg in #my_join(#arg, ', ')
 //   - *sqlf.Context: allowed only as the first argument
 //
 // Here are examples of legal names and function signatures:
@@ -45,7 +55,12 @@ var (
 //		"string": func(str string) (string, error)  {/* ... */},
 //		// #numbers(1,2)
 //		"numbers": func(ctx *sqlf.Context, a, b int) string  {/* ... */},
+//		// #if(debug) ... #end
+//		"debug": func() bool  {/* ... */},
 //	}
+//
+// Functions returning bool can only be used as conditions of #if, while
+// for functions returning string, a non-empty result means true.
 type FuncMap map[string]any
 
 type funcInfo struct {
@@ -60,18 +75,23 @@ type funcInfo struct {
 
 	nOut     int            // number of outputs
 	outTypes []reflect.Type // types of all outputs
+	outBool  bool           // if the first output is bool, aka a condition function
+
+	builtin bool // whether the function is a built-in one
 
-	joinTested bool  // whether the function has been tested for #join()
-	joinError  error // error to return when the function is not compatible with #join()
+	joinOnce  sync.Once // tests the function for #join() once, on its first use
+	joinError error     // error to return when the function is not compatible with #join()
 }
 
 // JoinCompatibilityError reports whether the function is compatible with #join().
+//
+// The function is tested by calling it with index 0 on the first use in
+// #join, rather than on registration. It's safe for concurrent use, since
+// a funcInfo can be shared by contexts with FuncEnv.
 func (f *funcInfo) JoinCompatibilityError() error {
-	if f.joinTested {
-		return f.joinError
-	}
-	f.joinError = joinCompatibility(f)
-	f.joinTested = true
+	f.joinOnce.Do(func() {
+		f.joinError = joinCompatibility(f)
+	})
 	return f.joinError
 }
 
@@ -89,7 +109,7 @@ func createValueFuncs(funcMap FuncMap) (map[string]*funcInfo, error) {
 func addValueFuncs(out map[string]*funcInfo, in FuncMap) error {
 	for name, fn := range in {
 		if !goodName(name) {
-			return fmt.Errorf("function name %q is not a valid identifier, only letters and underscore are allowed", name)
+			return fmt.Errorf("function name %q is not a valid identifier, only letters and underscore are allowed, except keywords if, else, end", name)
 		}
 		v := reflect.ValueOf(fn)
 		if v.Kind() != reflect.Func {
@@ -123,6 +143,7 @@ func addValueFuncs(out map[string]*funcInfo, in FuncMap) error {
 		for i := 0; i < nOut; i++ {
 			fun.outTypes[i] = typ.Out(i)
 		}
+		fun.outBool = nOut > 0 && fun.outTypes[0] == boolType
 
 		if err := goodFunc(fun); err != nil {
 			return fmt.Errorf("function #%s: %w", name, err)
@@ -135,7 +156,9 @@ func addValueFuncs(out map[string]*funcInfo, in FuncMap) error {
 
 // goodName reports whether the function name is a valid identifier.
 func goodName(name string) bool {
-	if name == "" {
+	switch name {
+	case "", "if", "else", "end":
+		// keywords of conditional sections
 		return false
 	}
 	for _, r := range name {
@@ -148,16 +171,16 @@ func goodName(name string) bool {
 
 // goodFunc reports whether the function or method has the right result signature.
 func goodFunc(f *funcInfo) error {
-	errInvalidFuncOutput := errors.New("invalid signature, expected func(...) (string, error); func(...) string; func(...);")
+	errInvalidFuncOutput := errors.New("invalid signature, expected func(...) (string, error); func(...) string; func(...); func(...) (bool, error); func(...) bool")
 	switch f.nOut {
 	case 0:
 		// ok
 	case 1:
-		if f.outTypes[0] != stringType {
+		if f.outTypes[0] != stringType && f.outTypes[0] != boolType {
 			return errInvalidFuncOutput
 		}
 	case 2:
-		if f.outTypes[0] != stringType || f.outTypes[1] != errorType {
+		if (f.outTypes[0] != stringType && f.outTypes[0] != boolType) || f.outTypes[1] != errorType {
 			return errInvalidFuncOutput
 		}
 	default:
@@ -173,7 +196,7 @@ func goodFunc(f *funcInfo) error {
 			t = t.Elem()
 		}
 		if !goodArgType(t) {
-			return fmt.Errorf("unsupported argument type '%s', allowed: number(int*, uint*, float*), string, bool, *sqlf.Context(as the first argument only)", t)
+			return fmt.Errorf("unsupported argument type '%s', allowed: number(int*, uint*, float*), string, bool, any, sqlf.FragmentBuilder, sqlf.FuncValue, *sqlf.Context(as the first argument only)", t)
 		}
 	}
 
@@ -182,7 +205,8 @@ func goodFunc(f *funcInfo) error {
 
 func goodArgType(t reflect.Type) bool {
 	kind := t.Kind()
-	return kind == reflect.String || kind == reflect.Bool || numberType(kind)
+	return kind == reflect.String || kind == reflect.Bool || numberType(kind) ||
+		t == anyType || t == fragmentBuilderType || t == funcValueType
 }
 
 func numberType(k reflect.Kind) bool {
@@ -196,8 +220,8 @@ func numberType(k reflect.Kind) bool {
 }
 
 func joinCompatibility(f *funcInfo) error {
-	errSig := errors.New("incompatible function signature, expected func(<number>) (string, error) or func(*sqlf.Context, <number>) (string, error)")
-	if f.nOut != 2 || f.outTypes[1] != errorType {
+	errSig := errors.New("incompatible function signature, expected func(<number|any>) (string, error) or func(*sqlf.Context, <number|any>) (string, error)")
+	if f.nOut != 2 || f.outBool || f.outTypes[1] != errorType {
 		return errSig
 	}
 	switch f.nIn {
@@ -206,7 +230,7 @@ func joinCompatibility(f *funcInfo) error {
 		if f.variadic {
 			t = t.Elem()
 		}
-		if !numberType(t.Kind()) {
+		if !numberType(t.Kind()) && t != anyType {
 			return errSig
 		}
 	case 2:
@@ -217,7 +241,7 @@ func joinCompatibility(f *funcInfo) error {
 		if f.variadic {
 			t = t.Elem()
 		}
-		if !numberType(t.Kind()) {
+		if !numberType(t.Kind()) && t != anyType {
 			return errSig
 		}
 	default:
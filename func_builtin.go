@@ -1,8 +1,10 @@
 package sqlf
 
 import (
+	"database/sql/driver"
 	"errors"
 	"fmt"
+	"reflect"
 	"strings"
 
 	"github.com/qjebbs/go-sqlf/v2/syntax"
@@ -15,6 +17,30 @@ var builtInFuncs = FuncMap{
 	"join":     funcJoin,
 }
 
+var (
+	// builtInEnv is the environment of the built-in functions,
+	// which is shared by all contexts.
+	builtInEnv *FuncEnv
+	// builtInArgCond is the condition version of #arg, see evalCondition.
+	builtInArgCond *funcInfo
+)
+
+func init() {
+	env, err := NewFuncEnv(builtInFuncs)
+	if err != nil {
+		panic(err)
+	}
+	for _, f := range env.funcs {
+		f.builtin = true
+	}
+	builtInEnv = env
+	funcs, err := createValueFuncs(FuncMap{"arg": condArg})
+	if err != nil {
+		panic(err)
+	}
+	builtInArgCond = funcs["arg"]
+}
+
 func funcJoin(ctx *Context, tmpl, separator string, indexes ...int) (string, error) {
 	var err error
 	var from, to int
@@ -34,7 +60,7 @@ func funcJoin(ctx *Context, tmpl, separator string, indexes ...int) (string, err
 	if to > 0 && from > to {
 		return "", fmt.Errorf("invalid index range %d to %d", from, to)
 	}
-	c, err := syntax.Parse(tmpl)
+	c, err := ctx.parse(tmpl)
 	if err != nil {
 		return "", fmt.Errorf("parse join template '%s': %w", tmpl, err)
 	}
@@ -47,10 +73,10 @@ func funcJoin(ctx *Context, tmpl, separator string, indexes ...int) (string, err
 		}
 		f, ok := ctx.fn(fn.Name)
 		if !ok {
-			return "", fmt.Errorf("unknown function #%s", fn.Name)
+			return "", &UnknownFuncError{Name: fn.Name}
 		}
-		if f.JoinCompatibilityError() != nil {
-			return "", fmt.Errorf("function #%s is incompatible with #join: %w", fn.Name, f.joinError)
+		if err := f.JoinCompatibilityError(); err != nil {
+			return "", fmt.Errorf("function #%s is incompatible with #join: %w", fn.Name, err)
 		}
 		call := &syntax.FuncCallExpr{
 			Name: fn.Name,
@@ -94,18 +120,77 @@ func funcJoin(ctx *Context, tmpl, separator string, indexes ...int) (string, err
 	return b.String(), nil
 }
 
-func funcArg(ctx *Context, i int) (string, error) {
+// funcArg builds the arg referenced by index or name.
+func funcArg(ctx *Context, ref any) (string, error) {
 	c, err := ctx.mustFragment()
 	if err != nil {
 		return "", err
 	}
+	i, err := c.ArgIndex(ref)
+	if err != nil {
+		return "", err
+	}
 	return c.Args.Build(ctx, i)
 }
 
-func funcFragment(ctx *Context, i int) (string, error) {
+// funcFragment builds the fragment referenced by index or name.
+func funcFragment(ctx *Context, ref any) (string, error) {
 	c, err := ctx.mustFragment()
 	if err != nil {
 		return "", err
 	}
+	i, err := c.FragmentIndex(ref)
+	if err != nil {
+		return "", err
+	}
 	return c.Fragments.Build(ctx, i)
 }
+
+// condArg reports whether the arg referenced by index or name is not nil.
+func condArg(ctx *Context, ref any) (bool, error) {
+	c, err := ctx.mustFragment()
+	if err != nil {
+		return false, err
+	}
+	i, err := c.ArgIndex(ref)
+	if err != nil {
+		return false, err
+	}
+	if i < 1 || i > len(c.Args) {
+		return false, &InvalidIndexError{Index: i}
+	}
+	c.Args[i-1].ReportUsed()
+	if c.Fragment == nil || i > len(c.Fragment.Args) {
+		// global args properties, whose values are invisible
+		return true, nil
+	}
+	v, _ := argValue(c.Fragment.Args[i-1])
+	if p, ok := v.(Param); ok {
+		return false, fmt.Errorf("#if(arg%d) of param %q: %w", i, p, ErrParamValue)
+	}
+	return !IsNil(v), nil
+}
+
+// IsNil reports whether v is a SQL NULL, that is nil, a nil pointer,
+// map, slice, etc., or a driver.Valuer whose value is nil, e.g. an
+// invalid sql.NullString.
+//
+// It's the nil semantics of #if(arg1), and is useful to the functions
+// and builders deciding on the values of args.
+func IsNil(v any) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Slice, reflect.Chan, reflect.Func, reflect.Interface:
+		if rv.IsNil() {
+			return true
+		}
+	}
+	if valuer, ok := v.(driver.Valuer); ok {
+		value, err := valuer.Value()
+		return err == nil && value == nil
+	}
+	return false
+}
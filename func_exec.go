@@ -3,17 +3,62 @@ package sqlf
 import (
 	"fmt"
 	"reflect"
+
+	"github.com/qjebbs/go-sqlf/v2/syntax"
 )
 
 func evalFunction(ctx *Context, name string, args []any) (string, error) {
 	function, ok := ctx.fn(name)
 	if !ok {
-		return "", fmt.Errorf("%q is not a defined function", name)
+		return "", &UnknownFuncError{Name: name}
 	}
 	return evalCall(ctx, function, args)
 }
 
 func evalCall(ctx *Context, f *funcInfo, args []any) (string, error) {
+	if f.outBool {
+		return "", fmt.Errorf("#%s returns bool, which can be used only as a condition of #if", f.name)
+	}
+	argv, err := callArgs(ctx, f, args)
+	if err != nil {
+		return "", err
+	}
+	v, err := safeCall(f.fn, argv)
+	if err != nil {
+		return "", fmt.Errorf("error calling #%s: %w", f.name, err)
+	}
+	if !v.IsValid() {
+		return "", nil
+	}
+	return convertString(v)
+}
+
+// evalCond evaluates the function as a condition, the result is true if
+// the function returns true, or a non-empty string.
+func evalCond(ctx *Context, f *funcInfo, args []any) (bool, error) {
+	if f.nOut == 0 {
+		return false, fmt.Errorf("#%s returns nothing, which cannot be used as a condition", f.name)
+	}
+	argv, err := callArgs(ctx, f, args)
+	if err != nil {
+		return false, err
+	}
+	v, err := safeCall(f.fn, argv)
+	if err != nil {
+		return false, fmt.Errorf("error calling #%s: %w", f.name, err)
+	}
+	if f.outBool {
+		return unwrap(v).Bool(), nil
+	}
+	s, err := convertString(v)
+	if err != nil {
+		return false, err
+	}
+	return s != "", nil
+}
+
+// callArgs checks the args and converts them to the arg list of f.
+func callArgs(ctx *Context, f *funcInfo, args []any) ([]reflect.Value, error) {
 	// check input args
 	nArgs := len(args)
 	nIn := f.nIn
@@ -24,10 +69,10 @@ func evalCall(ctx *Context, f *funcInfo, args []any) (string, error) {
 	}
 	if f.variadic {
 		if nArgs < nInFixed {
-			return "", fmt.Errorf("wrong number of args for #%s: want at least %d got %d", f.name, nInFixed, nArgs)
+			return nil, fmt.Errorf("wrong number of args for #%s: want at least %d got %d", f.name, nInFixed, nArgs)
 		}
 	} else if nArgs != nIn {
-		return "", fmt.Errorf("wrong number of args for #%s: want %d got %d", f.name, nIn, nArgs)
+		return nil, fmt.Errorf("wrong number of args for #%s: want %d got %d", f.name, nIn, nArgs)
 	}
 
 	// Prepare the arg list.
@@ -49,7 +94,7 @@ func evalCall(ctx *Context, f *funcInfo, args []any) (string, error) {
 		inType := f.inTypes[i]
 		argv[i], err = evalArg(inType, args[i])
 		if err != nil {
-			return "", fmt.Errorf("arg %d has wrong type for #%s: %w", i, f.name, err)
+			return nil, fmt.Errorf("arg %d has wrong type for #%s: %w", i, f.name, err)
 		}
 	}
 	// Now the ... args.
@@ -58,20 +103,17 @@ func evalCall(ctx *Context, f *funcInfo, args []any) (string, error) {
 		for ; i < len(args); i++ {
 			argv[i], err = evalArg(inType, args[i])
 			if err != nil {
-				return "", fmt.Errorf("arg %d has wrong type for #%s: %w", i, f.name, err)
+				return nil, fmt.Errorf("arg %d has wrong type for #%s: %w", i, f.name, err)
 			}
 		}
 	}
-	v, err := safeCall(f.fn, argv)
-	if err != nil {
-		return "", fmt.Errorf("error calling #%s: %w", f.name, err)
-	}
-	return v, nil
+	return argv, nil
 }
 
-// safeCall runs fun.Call(args), and returns the resulting value and error, if
-// any. If the call panics, the panic value is returned as an error.
-func safeCall(fun reflect.Value, args []reflect.Value) (val string, err error) {
+// safeCall runs fun.Call(args), and returns the first resulting value and error, if
+// any. If the call panics, the panic value is returned as an error. The value is
+// invalid if fun returns nothing.
+func safeCall(fun reflect.Value, args []reflect.Value) (val reflect.Value, err error) {
 	defer func() {
 		if r := recover(); r != nil {
 			if e, ok := r.(error); ok {
@@ -85,23 +127,15 @@ func safeCall(fun reflect.Value, args []reflect.Value) (val string, err error) {
 	ret := fun.Call(args)
 	switch len(ret) {
 	case 0:
-		return "", nil
+		return reflect.Value{}, nil
 	case 1:
-		rVal, err := convertString(ret[0])
-		if err != nil {
-			return "", fmt.Errorf("first return value: %w", err)
-		}
-		return rVal, nil
+		return ret[0], nil
 	default:
-		rVal, err := convertString(ret[0])
-		if err != nil {
-			return "", fmt.Errorf("first return value: %w", err)
-		}
 		rErr, err := convertError(ret[1])
 		if err != nil {
-			return "", fmt.Errorf("second return value: %w", err)
+			return reflect.Value{}, fmt.Errorf("second return value: %w", err)
 		}
-		return rVal, rErr
+		return ret[0], rErr
 	}
 }
 
@@ -116,7 +150,9 @@ func evalArg(typ reflect.Type, arg any) (reflect.Value, error) {
 		uint, uint8, uint16, uint32, uint64,
 		float32, float64:
 		argType = "number"
-		v = reflect.ValueOf(arg).Convert(typ)
+		if numberType(typ.Kind()) {
+			v = reflect.ValueOf(arg).Convert(typ)
+		}
 	case bool:
 		argType = "bool"
 		if typ.Kind() == reflect.Bool {
@@ -132,9 +168,22 @@ func evalArg(typ reflect.Type, arg any) (reflect.Value, error) {
 		if typ.Kind() == reflect.Interface {
 			v = reflect.Zero(typ)
 		}
+	case *syntax.FuncCallExpr:
+		argType = "function call #" + arg.Name
+		if typ == fragmentBuilderType || typ == anyType {
+			v = reflect.ValueOf(&funcCallFragment{arg})
+		}
+	case *syntax.FuncExpr:
+		argType = "function value #" + arg.Name
+		if typ == funcValueType || typ == anyType {
+			v = reflect.ValueOf(FuncValue{name: arg.Name})
+		}
 	default:
 		argType = reflect.TypeOf(arg).Name()
 	}
+	if !v.IsValid() && arg != nil && typ.Kind() == reflect.Interface && reflect.TypeOf(arg).Implements(typ) {
+		v = reflect.ValueOf(arg)
+	}
 	if v.IsValid() {
 		return v, nil
 	}
@@ -145,7 +194,7 @@ func convertString(v reflect.Value) (string, error) {
 	any := unwrap(v).Interface()
 	val, ok := any.(string)
 	if !ok {
-		return "", fmt.Errorf("expected string got %T", any)
+		return "", fmt.Errorf("first return value: expected string got %T", any)
 	}
 	return val, nil
 }
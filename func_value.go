@@ -0,0 +1,41 @@
+package sqlf
+
+import (
+	"github.com/qjebbs/go-sqlf/v2/syntax"
+)
+
+// FuncValue is a function passed as an argument of a preprocessing
+// function, e.g. #arg in #my_join(#arg, ', '), which allows
+// higher-order functions:
+//
+//	"my_join": func(ctx *sqlf.Context, fn sqlf.FuncValue, sep string) (string, error) {
+//		s1, err := fn.Call(ctx, 1)
+//		...
+//	}
+type FuncValue struct {
+	name string
+}
+
+// Name returns the name of the function.
+func (v FuncValue) Name() string {
+	return v.name
+}
+
+// Call calls the function with args, which are of the same types
+// as the ones written in the Raw: number, string, bool or nil.
+func (v FuncValue) Call(ctx *Context, args ...any) (string, error) {
+	return evalFunction(ctx, v.name, args)
+}
+
+var _ FragmentBuilder = (*funcCallFragment)(nil)
+
+// funcCallFragment is a nested function call passed as an argument,
+// which is evaluated when built.
+type funcCallFragment struct {
+	call *syntax.FuncCallExpr
+}
+
+// BuildFragment implements FragmentBuilder
+func (f *funcCallFragment) BuildFragment(ctx *Context) (string, error) {
+	return buildCall(ctx, f.call)
+}
@@ -0,0 +1,137 @@
+package sqlf_test
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/qjebbs/go-sqlf/v2"
+	"github.com/qjebbs/go-sqlf/v2/syntax"
+)
+
+// higherOrderFuncs are the custom functions accepting
+// fragments and function values.
+var higherOrderFuncs = sqlf.FuncMap{
+	// my_join joins the results of fn called with 1 to n
+	"my_join": func(ctx *sqlf.Context, fn sqlf.FuncValue, sep string) (string, error) {
+		var items []string
+		for i := 1; ; i++ {
+			s, err := fn.Call(ctx, i)
+			if errors.Is(err, sqlf.ErrInvalidIndex) {
+				break
+			}
+			if err != nil {
+				return "", err
+			}
+			items = append(items, s)
+		}
+		return strings.Join(items, sep), nil
+	},
+	"paren": func(ctx *sqlf.Context, f sqlf.FragmentBuilder) (string, error) {
+		s, err := f.BuildFragment(ctx)
+		if err != nil || s == "" {
+			return "", err
+		}
+		return "(" + s + ")", nil
+	},
+	"upper": func(s string) string {
+		return strings.ToUpper(s)
+	},
+}
+
+func TestFuncValues(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		name     string
+		fragment *sqlf.Fragment
+		want     string
+		wantArgs []any
+		wantErr  bool
+	}{
+		{
+			name:     "function value",
+			fragment: sqlf.Fa("IN (#my_join(#arg, ', '))", 1, 2, 3),
+			want:     "IN ($1, $2, $3)",
+			wantArgs: []any{1, 2, 3},
+		},
+		{
+			name:     "nested call",
+			fragment: sqlf.Ff("WHERE #paren(#f1) AND #paren(#f(2))", sqlf.Fa("a = $1", 1), sqlf.F("")),
+			want:     "WHERE (a = $1) AND",
+			wantArgs: []any{1},
+		},
+		{
+			name:     "deeply nested",
+			fragment: sqlf.Ff("#paren(#paren(#f1))", sqlf.F("a")),
+			want:     "((a))",
+		},
+		{
+			name:     "nested call to string",
+			fragment: sqlf.Ff("#upper(#f1)", sqlf.F("a")),
+			wantErr:  true,
+		},
+		{
+			name:     "unknown function value",
+			fragment: sqlf.Fa("#my_join(#foo, ', ')", 1),
+			wantErr:  true,
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, err := sqlf.ContextWithFuncs(sqlf.NewContext(syntax.Dollar), higherOrderFuncs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := tc.fragment.BuildFragment(ctx)
+			verr := sqlf.ValidateContext(ctx, tc.fragment)
+			if err != nil || verr != nil {
+				if tc.wantErr && err != nil && verr != nil {
+					return
+				}
+				t.Fatalf("build: %v, validate: %v", err, verr)
+			}
+			if tc.wantErr {
+				t.Fatal("want error, got nil")
+			}
+			if got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+			if args := ctx.Args(); !reflect.DeepEqual(args, tc.wantArgs) {
+				t.Errorf("got args %v, want %v", args, tc.wantArgs)
+			}
+		})
+	}
+}
+
+func ExampleFuncValue() {
+	ctx, err := sqlf.ContextWithFuncs(sqlf.NewContext(syntax.Dollar), sqlf.FuncMap{
+		"pair": func(ctx *sqlf.Context, fn sqlf.FuncValue, i int) (string, error) {
+			a, err := fn.Call(ctx, i)
+			if err != nil {
+				return "", err
+			}
+			b, err := fn.Call(ctx, i+1)
+			if err != nil {
+				return "", err
+			}
+			return "(" + a + ", " + b + ")", nil
+		},
+	})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	query, err := sqlf.Fa("VALUES #pair(#arg, 1), #pair(#arg, 3)", 1, 2, 3, 4).BuildFragment(ctx)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println(query)
+	fmt.Println(ctx.Args())
+	// Output:
+	// VALUES ($1, $2), ($3, $4)
+	// [1 2 3 4]
+}
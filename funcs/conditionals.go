@@ -0,0 +1,85 @@
+package funcs
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/qjebbs/go-sqlf/v2"
+)
+
+// Coalesce implements #coalesce(refs...).
+func Coalesce(ctx *sqlf.Context, refs ...any) (string, error) {
+	items, err := buildNotEmpty(ctx, refs)
+	if err != nil {
+		return "", err
+	}
+	switch len(items) {
+	case 0:
+		return "", nil
+	case 1:
+		return items[0], nil
+	default:
+		return "COALESCE(" + strings.Join(items, ", ") + ")", nil
+	}
+}
+
+// NotEmpty implements #not_empty(sep, refs...).
+func NotEmpty(ctx *sqlf.Context, sep string, refs ...any) (string, error) {
+	items, err := buildNotEmpty(ctx, refs)
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(items, sep), nil
+}
+
+// RawIf implements #raw_if(ref, text).
+func RawIf(ctx *sqlf.Context, ref any, text string) (string, error) {
+	fc, err := mustFragment(ctx)
+	if err != nil {
+		return "", err
+	}
+	i, err := fc.ArgIndex(ref)
+	if err != nil {
+		return "", err
+	}
+	v, err := fc.ArgValue(i)
+	if err != nil {
+		return "", err
+	}
+	if p, ok := v.(sqlf.Param); ok {
+		return "", fmt.Errorf("#raw_if of param %q: %w", p, sqlf.ErrParamValue)
+	}
+	if b, ok := v.(bool); (ok && !b) || sqlf.IsNil(v) {
+		return "", nil
+	}
+	return text, nil
+}
+
+// buildNotEmpty builds the fragments referenced by refs, or all fragments
+// if refs is empty, and returns the ones not empty.
+func buildNotEmpty(ctx *sqlf.Context, refs []any) ([]string, error) {
+	fc, err := mustFragment(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(refs) == 0 {
+		for i := 1; i <= len(fc.Fragments); i++ {
+			refs = append(refs, i)
+		}
+	}
+	var r []string
+	for _, ref := range refs {
+		i, err := fc.FragmentIndex(ref)
+		if err != nil {
+			return nil, err
+		}
+		s, err := fc.Fragments.Build(ctx, i)
+		if err != nil {
+			return nil, err
+		}
+		if s != "" {
+			r = append(r, s)
+		}
+	}
+	return r, nil
+}
@@ -0,0 +1,70 @@
+// Package funcs is an opt-in library of preprocessing functions
+// for sqlf, register them with sqlf.ContextWithFuncs:
+//
+//	ctx, err := sqlf.ContextWithFuncs(sqlf.NewContext(syntax.Dollar), funcs.All())
+//
+// or, to reflect them once for all builds:
+//
+//	env, err := sqlf.NewFuncEnv(funcs.All())
+//	ctx := sqlf.ContextWithFuncEnv(sqlf.NewContext(syntax.Dollar), env)
+//
+// Lists, for args of the fragment:
+//
+//   - #in(ref): expands the arg, usually a slice, into a placeholder list,
+//     e.g. `id IN (#in(1))` builds `id IN ($1, $2, $3)`.
+//     An empty slice builds the expression set by sqlf.WithEmptySliceExpr,
+//     or fails with sqlf.ErrEmptySlice, see it for the trap of NOT IN.
+//   - #tuple(refs...): a parenthesized tuple of the args, slices are
+//     expanded, e.g. `(a, b) = #tuple(1, 2)`. All args if no refs given.
+//   - #values(cols): groups all args into rows of cols values,
+//     e.g. `VALUES #values(2)` builds `VALUES ($1, $2), ($3, $4)`.
+//     It fails if there are no args.
+//
+// Conditionals, for fragments of the fragment:
+//
+//   - #coalesce(refs...): COALESCE of the fragments not built empty,
+//     or the only one of them, e.g. `#coalesce(1, 2)`.
+//   - #not_empty(sep, refs...): joins the fragments not built empty
+//     with sep, e.g. `WHERE #not_empty(' AND ', 1, 2)`.
+//     All fragments if no refs given.
+//   - #raw_if(ref, text): writes the raw text if the arg is true, or not
+//     NULL (see sqlf.IsNil) for non-bool values, e.g.
+//     `SELECT #raw_if(1, 'DISTINCT') *`. The arg is not committed, and
+//     it fails with sqlf.ErrParamValue for a sqlf.Param.
+//
+// A ref is an index starting from 1, or a name (see
+// sqlf.Fragment.AppendNamedArg and sqlf.Fragment.AppendNamedFragment).
+// The functions with a single ref are compatible with #join.
+package funcs
+
+import (
+	"github.com/qjebbs/go-sqlf/v2"
+)
+
+// Lists returns the functions for list args: #in, #tuple and #values.
+func Lists() sqlf.FuncMap {
+	return sqlf.FuncMap{
+		"in":     In,
+		"tuple":  Tuple,
+		"values": Values,
+	}
+}
+
+// Conditionals returns the functions building conditionally:
+// #coalesce, #not_empty and #raw_if.
+func Conditionals() sqlf.FuncMap {
+	return sqlf.FuncMap{
+		"coalesce":  Coalesce,
+		"not_empty": NotEmpty,
+		"raw_if":    RawIf,
+	}
+}
+
+// All returns all functions of the package.
+func All() sqlf.FuncMap {
+	r := Lists()
+	for name, fn := range Conditionals() {
+		r[name] = fn
+	}
+	return r
+}
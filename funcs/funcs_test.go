@@ -0,0 +1,180 @@
+package funcs_test
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/qjebbs/go-sqlf/v2"
+	"github.com/qjebbs/go-sqlf/v2/funcs"
+	"github.com/qjebbs/go-sqlf/v2/syntax"
+)
+
+func TestFuncs(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		name     string
+		fragment *sqlf.Fragment
+		want     string
+		wantArgs []any
+		wantErr  bool
+	}{
+		{
+			name:     "in",
+			fragment: sqlf.Fa("id IN (#in(1)) AND type = $2", []int{1, 2, 3}, 4),
+			want:     "id IN ($1, $2, $3) AND type = $4",
+			wantArgs: []any{1, 2, 3, 4},
+		},
+		{
+			name:     "in empty",
+			fragment: sqlf.Fa("id IN (#in(1))", []int{}),
+			wantErr:  true,
+		},
+		{
+			name:     "tuple empty",
+			fragment: sqlf.Fa("#tuple(1)", []int{}),
+			wantErr:  true,
+		},
+		{
+			name:     "in scalar and bytes",
+			fragment: sqlf.Fa("#in(1) #in(2)", 1, []byte("a")),
+			want:     "$1 $2",
+			wantArgs: []any{1, []byte("a")},
+		},
+		{
+			name:     "in named",
+			fragment: sqlf.F("id IN (#in(ids))").AppendNamedArg("ids", []string{"a", "b"}),
+			want:     "id IN ($1, $2)",
+			wantArgs: []any{"a", "b"},
+		},
+		{
+			name:     "in join",
+			fragment: sqlf.Fa("#join('#in', ', ')", []int{1, 2}, 3),
+			want:     "$1, $2, $3",
+			wantArgs: []any{1, 2, 3},
+		},
+		{
+			name:     "tuple",
+			fragment: sqlf.Fa("(a, b, c) = #tuple(1, 2)", 1, []int{2, 3}),
+			want:     "(a, b, c) = ($1, $2, $3)",
+			wantArgs: []any{1, 2, 3},
+		},
+		{
+			name:     "tuple all",
+			fragment: sqlf.Fa("#tuple()", 1, 2),
+			want:     "($1, $2)",
+			wantArgs: []any{1, 2},
+		},
+		{
+			name:     "values",
+			fragment: sqlf.Fa("INSERT INTO foo (a, b) VALUES #values(2)", 1, 2, 3, 4),
+			want:     "INSERT INTO foo (a, b) VALUES ($1, $2), ($3, $4)",
+			wantArgs: []any{1, 2, 3, 4},
+		},
+		{
+			name:     "values empty",
+			fragment: sqlf.F("VALUES #values(2)"),
+			wantErr:  true,
+		},
+		{
+			name:     "values mismatch",
+			fragment: sqlf.Fa("#values(2)", 1, 2, 3),
+			wantErr:  true,
+		},
+		{
+			name: "coalesce",
+			fragment: sqlf.Ff(
+				"#coalesce(1, 2, 3)",
+				sqlf.F("a"), sqlf.F(""), sqlf.F("b"),
+			),
+			want: "COALESCE(a, b)",
+		},
+		{
+			name:     "coalesce single",
+			fragment: sqlf.Ff("#coalesce(1, 2)", sqlf.F(""), sqlf.F("b")),
+			want:     "b",
+		},
+		{
+			name: "not empty",
+			fragment: sqlf.Ff(
+				"WHERE #not_empty(' AND ')",
+				sqlf.Fa("a = $1", 1), sqlf.F(""), sqlf.Fa("b = $1", 2),
+			),
+			want:     "WHERE a = $1 AND b = $2",
+			wantArgs: []any{1, 2},
+		},
+		{
+			name:     "raw if",
+			fragment: sqlf.Fa("SELECT #raw_if(1, 'DISTINCT') *#raw_if(2, ' FOR UPDATE')", true, false),
+			want:     "SELECT DISTINCT *",
+		},
+		{
+			name:     "raw if nil pointer",
+			fragment: sqlf.Fa("SELECT *#raw_if(1, ' FOR UPDATE')", (*int)(nil)),
+			want:     "SELECT *",
+		},
+		{
+			name:     "raw if param",
+			fragment: sqlf.Fa("SELECT *#raw_if(1, ' FOR UPDATE')", sqlf.Param("lock")),
+			wantErr:  true,
+		},
+		{
+			name: "in skipped by if",
+			fragment: sqlf.F("SELECT 1#if(f1) WHERE id IN (#in(1))#end").
+				WithFragments(sqlf.F("")).
+				WithArgs([]int{1, 2}),
+			want: "SELECT 1",
+		},
+		{
+			name:     "invalid index",
+			fragment: sqlf.Fa("#in(2)", 1),
+			wantErr:  true,
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, err := sqlf.ContextWithFuncs(sqlf.NewContext(syntax.Dollar), funcs.All())
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := tc.fragment.BuildFragment(ctx)
+			if err != nil {
+				if tc.wantErr {
+					return
+				}
+				t.Fatal(err)
+			}
+			if tc.wantErr {
+				t.Fatal("want error, got nil")
+			}
+			if got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+			if args := ctx.Args(); !reflect.DeepEqual(args, tc.wantArgs) {
+				t.Errorf("got args %v, want %v", args, tc.wantArgs)
+			}
+		})
+	}
+}
+
+func Example() {
+	ctx, err := sqlf.ContextWithFuncs(sqlf.NewContext(syntax.Dollar), funcs.All())
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fragment := sqlf.F("SELECT #raw_if(distinct, 'DISTINCT') * FROM foo WHERE id IN (#in(ids))").
+		AppendNamedArg("distinct", true).
+		AppendNamedArg("ids", []int{1, 2, 3})
+	query, err := fragment.BuildFragment(ctx)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println(query)
+	fmt.Println(ctx.Args())
+	// Output:
+	// SELECT DISTINCT * FROM foo WHERE id IN ($1, $2, $3)
+	// [1 2 3]
+}
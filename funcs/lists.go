@@ -0,0 +1,121 @@
+package funcs
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/qjebbs/go-sqlf/v2"
+)
+
+// In implements #in(ref).
+func In(ctx *sqlf.Context, ref any) (string, error) {
+	fc, err := mustFragment(ctx)
+	if err != nil {
+		return "", err
+	}
+	i, err := fc.ArgIndex(ref)
+	if err != nil {
+		return "", err
+	}
+	v, err := fc.ArgValue(i)
+	if err != nil {
+		return "", err
+	}
+	items := commitList(ctx, v, nil)
+	if len(items) == 0 {
+		return ctx.EmptySliceExpr()
+	}
+	return strings.Join(items, ", "), nil
+}
+
+// Tuple implements #tuple(refs...).
+func Tuple(ctx *sqlf.Context, refs ...any) (string, error) {
+	fc, err := mustFragment(ctx)
+	if err != nil {
+		return "", err
+	}
+	if len(refs) == 0 {
+		for i := 1; i <= len(fc.Args); i++ {
+			refs = append(refs, i)
+		}
+	}
+	var items []string
+	for _, ref := range refs {
+		i, err := fc.ArgIndex(ref)
+		if err != nil {
+			return "", err
+		}
+		v, err := fc.ArgValue(i)
+		if err != nil {
+			return "", err
+		}
+		items = commitList(ctx, v, items)
+	}
+	if len(items) == 0 {
+		expr, err := ctx.EmptySliceExpr()
+		if err != nil {
+			return "", err
+		}
+		return "(" + expr + ")", nil
+	}
+	return "(" + strings.Join(items, ", ") + ")", nil
+}
+
+// Values implements #values(cols).
+func Values(ctx *sqlf.Context, cols int) (string, error) {
+	fc, err := mustFragment(ctx)
+	if err != nil {
+		return "", err
+	}
+	if cols < 1 {
+		return "", fmt.Errorf("invalid columns count %d", cols)
+	}
+	n := len(fc.Args)
+	if n == 0 {
+		return "", errors.New("no args to build values")
+	}
+	if n%cols != 0 {
+		return "", fmt.Errorf("%d args cannot be grouped into rows of %d values", n, cols)
+	}
+	b := new(strings.Builder)
+	for i := 1; i <= n; i++ {
+		switch {
+		case i == 1:
+			b.WriteString("(")
+		case (i-1)%cols == 0:
+			b.WriteString("), (")
+		default:
+			b.WriteString(", ")
+		}
+		s, err := fc.Args.Build(ctx, i)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(s)
+	}
+	b.WriteString(")")
+	return b.String(), nil
+}
+
+// commitList commits v, or each item of v if it's a list,
+// and appends the bindvars to r.
+func commitList(ctx *sqlf.Context, v any, r []string) []string {
+	if !sqlf.IsList(v) {
+		return append(r, ctx.CommitArg(v))
+	}
+	rv := reflect.ValueOf(v)
+	for i := 0; i < rv.Len(); i++ {
+		r = append(r, ctx.CommitArg(rv.Index(i).Interface()))
+	}
+	return r
+}
+
+func mustFragment(ctx *sqlf.Context) (*sqlf.FragmentContext, error) {
+	fc, ok := ctx.Fragment()
+	if !ok {
+		return nil, fmt.Errorf("no fragment context")
+	}
+	return fc, nil
+}
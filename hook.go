@@ -0,0 +1,161 @@
+package sqlf
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/qjebbs/go-sqlf/v2/syntax"
+)
+
+// BuildHook is the hook of building queries with BuildQuery, which is
+// useful for logging, metrics and tracing.
+//
+// The same *BuildEvent is passed to OnBuildStart and OnBuildEnd of a
+// build, so that the hook can relate them by the pointer.
+type BuildHook interface {
+	// OnBuildStart is called before the building.
+	OnBuildStart(e *BuildEvent)
+	// OnBuildEnd is called after the building, with the result
+	// filled in the event.
+	OnBuildEnd(e *BuildEvent)
+}
+
+// BuildEvent is the event of building a query.
+type BuildEvent struct {
+	// Ctx is the context.Context of the building set by WithGoContext,
+	// or context.Background(). A hook can replace it in OnBuildStart for
+	// the hooks after it, e.g. with the one carrying a tracing span.
+	Ctx     context.Context
+	Context *Context        // the context of the building
+	Builder FragmentBuilder // the builder being built
+	Start   time.Time       // the start time of the building
+
+	// The results, which are set before OnBuildEnd is called.
+	Query    string
+	Args     []any
+	Duration time.Duration
+	Err      error
+}
+
+var (
+	globalBuildHookMu sync.RWMutex
+	globalBuildHook   BuildHook
+)
+
+// SetBuildHook sets the global BuildHook, which is used by the builds
+// without WithBuildHook. A nil hook disables it.
+func SetBuildHook(h BuildHook) {
+	globalBuildHookMu.Lock()
+	defer globalBuildHookMu.Unlock()
+	globalBuildHook = h
+}
+
+// GlobalBuildHook returns the global BuildHook set by SetBuildHook.
+func GlobalBuildHook() BuildHook {
+	globalBuildHookMu.RLock()
+	defer globalBuildHookMu.RUnlock()
+	return globalBuildHook
+}
+
+// WithBuildHook sets the BuildHook of the context, which overrides the
+// global one. WithBuildHook(nil) disables the hooks for the context.
+func WithBuildHook(h BuildHook) ContextOption {
+	return func(o *contextOptions) {
+		o.buildHook = h
+		o.buildHookSet = true
+	}
+}
+
+// WithGoContext sets the context.Context of the building, which is
+// passed to the BuildHook with BuildEvent.Ctx, so that the hooks can
+// report in the scope of the caller, e.g. parent the tracing spans.
+//
+// The builders read it with GoContext for the request-scoped values of
+// the caller, and share the state of the building with ContextWithValue.
+func WithGoContext(ctx context.Context) ContextOption {
+	return func(o *contextOptions) {
+		o.goCtx = ctx
+	}
+}
+
+// GoContext returns the context.Context set by WithGoContext,
+// or context.Background() if not set.
+func (c *Context) GoContext() context.Context {
+	if ctx := c.root().goCtx; ctx != nil {
+		return ctx
+	}
+	return context.Background()
+}
+
+// buildHook returns the BuildHook of the context.
+func (c *Context) buildHook() BuildHook {
+	root := c.root()
+	if root.hookSet {
+		return root.hook
+	}
+	return GlobalBuildHook()
+}
+
+// JoinBuildHooks returns a BuildHook calling the hooks in order,
+// the nil ones are skipped.
+func JoinBuildHooks(hooks ...BuildHook) BuildHook {
+	r := make(buildHooks, 0, len(hooks))
+	for _, h := range hooks {
+		if h != nil {
+			r = append(r, h)
+		}
+	}
+	switch len(r) {
+	case 0:
+		return nil
+	case 1:
+		return r[0]
+	}
+	return r
+}
+
+type buildHooks []BuildHook
+
+func (hooks buildHooks) OnBuildStart(e *BuildEvent) {
+	for _, h := range hooks {
+		h.OnBuildStart(e)
+	}
+}
+
+func (hooks buildHooks) OnBuildEnd(e *BuildEvent) {
+	for _, h := range hooks {
+		h.OnBuildEnd(e)
+	}
+}
+
+// BuildQuery builds b as a full query, with a new context created by
+// NewContext(bindVarStyle, options...), and calls the BuildHook of the
+// context around the building.
+func BuildQuery(b FragmentBuilder, bindVarStyle syntax.BindVarStyle, options ...ContextOption) (query string, args []any, err error) {
+	ctx := NewContext(bindVarStyle, options...)
+	hook := ctx.buildHook()
+	if hook == nil {
+		return buildQuery(ctx, b)
+	}
+	e := &BuildEvent{
+		Ctx:     ctx.GoContext(),
+		Context: ctx,
+		Builder: b,
+		Start:   time.Now(),
+	}
+	hook.OnBuildStart(e)
+	query, args, err = buildQuery(ctx, b)
+	e.Query, e.Args, e.Err = query, args, err
+	e.Duration = time.Since(e.Start)
+	hook.OnBuildEnd(e)
+	return query, args, err
+}
+
+func buildQuery(ctx *Context, b FragmentBuilder) (query string, args []any, err error) {
+	query, err = b.BuildFragment(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+	return query, ctx.Args(), nil
+}
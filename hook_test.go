@@ -0,0 +1,66 @@
+package sqlf_test
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/qjebbs/go-sqlf/v2"
+	"github.com/qjebbs/go-sqlf/v2/syntax"
+)
+
+// recordHook records the build events.
+type recordHook struct {
+	name   string
+	events *[]string
+}
+
+func (h recordHook) OnBuildStart(e *sqlf.BuildEvent) {
+	*h.events = append(*h.events, h.name+" start")
+}
+
+func (h recordHook) OnBuildEnd(e *sqlf.BuildEvent) {
+	if e.Err != nil {
+		*h.events = append(*h.events, fmt.Sprintf("%s end: error", h.name))
+		return
+	}
+	*h.events = append(*h.events, fmt.Sprintf("%s end: %s %v", h.name, e.Query, e.Args))
+}
+
+func TestBuildHook(t *testing.T) {
+	// not parallel, it changes the global hook
+	var events []string
+	global := recordHook{"global", &events}
+	sqlf.SetBuildHook(global)
+	defer sqlf.SetBuildHook(nil)
+
+	f := sqlf.Fa("id = $1", 1)
+	if _, _, err := f.BuildQuery(syntax.Dollar); err != nil {
+		t.Fatal(err)
+	}
+	_, _, err := sqlf.BuildQuery(f, syntax.Question, sqlf.WithBuildHook(
+		sqlf.JoinBuildHooks(recordHook{"a", &events}, nil, recordHook{"b", &events}),
+	))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := sqlf.BuildQuery(f, syntax.Dollar, sqlf.WithBuildHook(nil)); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := sqlf.F("#c1").BuildQuery(syntax.Dollar); err == nil {
+		t.Fatal("want error, got nil")
+	}
+	want := []string{
+		"global start",
+		"global end: id = $1 [1]",
+		"a start",
+		"b start",
+		"a end: id = ? [1]",
+		"b end: id = ? [1]",
+		"global start",
+		"global end: error",
+	}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("got:\n%q\nwant:\n%q", events, want)
+	}
+}
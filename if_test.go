@@ -0,0 +1,228 @@
+package sqlf_test
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/qjebbs/go-sqlf/v2"
+	"github.com/qjebbs/go-sqlf/v2/syntax"
+)
+
+func TestIf(t *testing.T) {
+	t.Parallel()
+	var nilPtr *int
+	testCases := []struct {
+		name     string
+		fragment *sqlf.Fragment
+		debug    bool
+		want     string
+		wantArgs []any
+		wantErr  bool
+	}{
+		{
+			name: "fragment not empty",
+			fragment: sqlf.Ff(
+				"SELECT * FROM foo#if(f1) WHERE #f1#end",
+				sqlf.Fa("id = $1", 1),
+			),
+			want:     "SELECT * FROM foo WHERE id = $1",
+			wantArgs: []any{1},
+		},
+		{
+			name: "fragment empty",
+			fragment: sqlf.Ff(
+				"SELECT * FROM foo#if(f1) WHERE #f1#end",
+				sqlf.F(""),
+			),
+			want: "SELECT * FROM foo",
+		},
+		{
+			name: "skipped branch references",
+			fragment: sqlf.Ff(
+				"#if(f1)#f2#else #join('#arg', ', ')#end",
+				sqlf.F(""), sqlf.Fa("$1", 1),
+			).WithArgs(2, 3),
+			want:     "$1, $2",
+			wantArgs: []any{2, 3},
+		},
+		{
+			name: "arg",
+			fragment: sqlf.Fa(
+				"a = 1#if(arg1) AND b = $1#end#if(arg, 2) AND c = $2#end",
+				nil, 2,
+			),
+			want:     "a = 1 AND c = $1",
+			wantArgs: []any{2},
+		},
+		{
+			name:     "typed nil arg",
+			fragment: sqlf.Fa("#if(arg1)$1#else NULL#end", nilPtr),
+			want:     "NULL",
+		},
+		{
+			name:     "nil slice arg",
+			fragment: sqlf.Fa("#if(arg1)$1#else NULL#end", []int(nil)),
+			want:     "NULL",
+		},
+		{
+			name:     "empty slice arg",
+			fragment: sqlf.Fa("#if(arg1)$1#else NULL#end", []int{}),
+			want:     "$1",
+			wantArgs: []any{[]int{}},
+		},
+		{
+			name:     "null valuer arg",
+			fragment: sqlf.Fa("#if(arg1)$1#else NULL#end", sql.NullString{}),
+			want:     "NULL",
+		},
+		{
+			name:     "valid valuer arg",
+			fragment: sqlf.Fa("#if(arg1)$1#else NULL#end", sql.NullString{String: "a", Valid: true}),
+			want:     "$1",
+			wantArgs: []any{sql.NullString{String: "a", Valid: true}},
+		},
+		{
+			name:     "global",
+			fragment: sqlf.F("SELECT#if(debug) *#else id#end FROM foo"),
+			debug:    true,
+			want:     "SELECT * FROM foo",
+		},
+		{
+			name:     "global false",
+			fragment: sqlf.F("SELECT#if(debug) *#else id#end FROM foo"),
+			want:     "SELECT id FROM foo",
+		},
+		{
+			name:     "bool function outside #if",
+			fragment: sqlf.F("#debug()"),
+			wantErr:  true,
+		},
+		{
+			name:     "unknown condition",
+			fragment: sqlf.F("#if(foo) a #end"),
+			wantErr:  true,
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, err := sqlf.ContextWithFuncs(sqlf.NewContext(syntax.Dollar), sqlf.FuncMap{
+				"debug": func() bool { return tc.debug },
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := tc.fragment.BuildFragment(ctx)
+			if err != nil {
+				if tc.wantErr {
+					return
+				}
+				t.Fatal(err)
+			}
+			if tc.wantErr {
+				t.Fatal("want error, got nil")
+			}
+			if got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+			if !reflect.DeepEqual(ctx.Args(), tc.wantArgs) {
+				t.Errorf("got %v, want %v", ctx.Args(), tc.wantArgs)
+			}
+			if err := sqlf.ValidateContext(ctx, tc.fragment); err != nil {
+				t.Errorf("validate: %v", err)
+			}
+		})
+	}
+}
+
+func Example_if() {
+	where := sqlf.F("#join('#fragment', ' AND ')")
+	query, args, _ := sqlf.Ff(
+		"SELECT * FROM foo#if(f1) WHERE #f1#end", where,
+	).BuildQuery(syntax.Dollar)
+	fmt.Println(query)
+	fmt.Println(args)
+
+	where.AppendFragments(sqlf.Fa("id = $1", 1))
+	query, args, _ = sqlf.Ff(
+		"SELECT * FROM foo#if(f1) WHERE #f1#end", where,
+	).BuildQuery(syntax.Dollar)
+	fmt.Println(query)
+	fmt.Println(args)
+	// Output:
+	// SELECT * FROM foo
+	// []
+	// SELECT * FROM foo WHERE id = $1
+	// [1]
+}
+
+// countBuilder counts the builds of the fragment.
+type countBuilder struct {
+	*sqlf.Fragment
+	n int
+}
+
+func (b *countBuilder) BuildFragment(ctx *sqlf.Context) (string, error) {
+	b.n++
+	return b.Fragment.BuildFragment(ctx)
+}
+
+func TestIfBuildOnce(t *testing.T) {
+	t.Parallel()
+	for _, style := range []syntax.BindVarStyle{syntax.Dollar, syntax.Question} {
+		leaf := &countBuilder{Fragment: sqlf.Fa("a = $1 AND b = $2", 1, 2)}
+		var f sqlf.FragmentBuilder = leaf
+		for i := 0; i < 10; i++ {
+			f = sqlf.Ff("#if(f1)(#f1)#end", f)
+		}
+		f = sqlf.Ff("#f1 AND c = $1", f).WithArgs(3)
+		query, args, err := sqlf.BuildQuery(f, style)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if leaf.n != 1 {
+			t.Errorf("style %v: the leaf is built %d times, want 1", style, leaf.n)
+		}
+		want := "((((((((((a = $1 AND b = $2)))))))))) AND c = $3"
+		if style == syntax.Question {
+			want = "((((((((((a = ? AND b = ?)))))))))) AND c = ?"
+		}
+		if query != want {
+			t.Errorf("style %v: got:\n%s\nwant:\n%s", style, query, want)
+		}
+		if wantArgs := []any{1, 2, 3}; !reflect.DeepEqual(args, wantArgs) {
+			t.Errorf("style %v: got args %v, want %v", style, args, wantArgs)
+		}
+	}
+}
+
+// markBuilder builds a text containing a sequence like the internal
+// marks of the recorded args.
+type markBuilder struct{}
+
+func (markBuilder) BuildFragment(ctx *sqlf.Context) (string, error) {
+	return "x = " + ctx.CommitArg(1) + " AND y = 'a\x001\x00b'", nil
+}
+
+func TestIfMarkLikeText(t *testing.T) {
+	t.Parallel()
+	want := "c = $1 AND x = $2 AND y = 'a\x001\x00b'"
+	for _, raw := range []string{
+		"c = $1 AND #f1",
+		"c = $1 AND #if(f1)#f1#end",
+		"c = $1 AND #if(f1)#if(f1)#f1#end#end",
+	} {
+		query, args, err := sqlf.Fa(raw, 0).WithFragments(markBuilder{}).BuildQuery(syntax.Dollar)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if query != want {
+			t.Errorf("%s: got %q, want %q", raw, query, want)
+		}
+		if wantArgs := []any{0, 1}; !reflect.DeepEqual(args, wantArgs) {
+			t.Errorf("%s: got args %v, want %v", raw, args, wantArgs)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package sqlf
+
+import (
+	"errors"
+	"fmt"
+)
+
+// checkNames checks the names of the args and fragments of f.
+func (f *Fragment) checkNames() error {
+	if err := checkNames("arg", f.ArgNames, len(f.Args)); err != nil {
+		return err
+	}
+	return checkNames("fragment", f.FragmentNames, len(f.Fragments))
+}
+
+func checkNames(kind string, names []string, n int) error {
+	if len(names) > n {
+		return fmt.Errorf("%d %s names for %d %ss", len(names), kind, n, kind)
+	}
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		if name == "" {
+			continue
+		}
+		if seen[name] {
+			return fmt.Errorf("duplicate %s name '%s'", kind, name)
+		}
+		seen[name] = true
+	}
+	return nil
+}
+
+// refIndex resolves the reference to an arg or fragment, which is
+// either an index starting from 1, or one of the names.
+func refIndex(ref any, names []string) (int, error) {
+	switch ref := ref.(type) {
+	case int:
+		return ref, nil
+	case float64:
+		return int(ref), nil
+	case string:
+		for i, name := range names {
+			if name != "" && name == ref {
+				return i + 1, nil
+			}
+		}
+		return 0, &InvalidIndexError{Name: ref}
+	default:
+		return 0, fmt.Errorf("invalid reference of %T, want index or name", ref)
+	}
+}
+
+// ArgIndex resolves the reference to an arg of current fragment,
+// which is an index starting from 1, or a name of Fragment.ArgNames.
+func (c *FragmentContext) ArgIndex(ref any) (int, error) {
+	var names []string
+	if c.Fragment != nil {
+		names = c.Fragment.ArgNames
+	}
+	return refIndex(ref, names)
+}
+
+// FragmentIndex resolves the reference to a fragment of current fragment,
+// which is an index starting from 1, or a name of Fragment.FragmentNames.
+func (c *FragmentContext) FragmentIndex(ref any) (int, error) {
+	var names []string
+	if c.Fragment != nil {
+		names = c.Fragment.FragmentNames
+	}
+	return refIndex(ref, names)
+}
+
+// ArgValue returns the value of the arg at i and reports it used,
+// without committing it, so that the caller can commit it as needed.
+//
+// It returns an error if the values are invisible, e.g., the Args are
+// global properties created by NewArgsProperties.
+func (c *FragmentContext) ArgValue(i int) (any, error) {
+	if i < 1 || i > len(c.Args) {
+		return nil, &InvalidIndexError{Index: i}
+	}
+	if c.Fragment == nil || i > len(c.Fragment.Args) {
+		return nil, errors.New("arg values are invisible out of a fragment")
+	}
+	c.Args[i-1].ReportUsed()
+	v, _ := argValue(c.Fragment.Args[i-1])
+	return v, nil
+}
@@ -0,0 +1,174 @@
+package sqlf_test
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/qjebbs/go-sqlf/v2"
+	"github.com/qjebbs/go-sqlf/v2/syntax"
+)
+
+func TestNamedReferences(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		name     string
+		fragment *sqlf.Fragment
+		want     string
+		wantArgs []any
+		wantErr  error
+	}{
+		{
+			name: "quoted and bare names",
+			fragment: sqlf.F("SELECT * FROM users WHERE #f('user_filter') AND #f(status_filter)").
+				AppendNamedFragment("user_filter", sqlf.Fa("id = $1", 1)).
+				AppendNamedFragment("status_filter", sqlf.Fa("status = $1", "active")),
+			want:     "SELECT * FROM users WHERE id = $1 AND status = $2",
+			wantArgs: []any{1, "active"},
+		},
+		{
+			name: "named args",
+			fragment: sqlf.F("id > #arg('min_id') AND id < #arg(max_id)").
+				AppendNamedArg("min_id", 1).
+				AppendNamedArg("max_id", 9),
+			want:     "id > $1 AND id < $2",
+			wantArgs: []any{1, 9},
+		},
+		{
+			name: "mixed with positional",
+			fragment: sqlf.Fa("$1 #arg('b') #arg3", "a").
+				AppendNamedArg("b", "b").
+				AppendArgs("c"),
+			want:     "$1 $2 $3",
+			wantArgs: []any{"a", "b", "c"},
+		},
+		{
+			name: "join in declared order",
+			fragment: sqlf.F("#join('#f', ' AND ')").
+				AppendNamedFragment("b", sqlf.F("b")).
+				AppendNamedFragment("a", sqlf.F("a")),
+			want: "b AND a",
+		},
+		{
+			name: "if named arg",
+			fragment: sqlf.F("a = 1#if(arg, 'b') AND b = #arg(b)#end").
+				AppendNamedArg("b", nil),
+			want: "a = 1",
+		},
+		{
+			name: "with args resets names",
+			fragment: sqlf.F("#arg1 #arg2").
+				AppendNamedArg("a", 1).
+				AppendNamedArg("b", 2).
+				WithArgs(3),
+			wantErr: sqlf.ErrInvalidIndex,
+		},
+		{
+			name: "with args stale name",
+			fragment: sqlf.F("#arg(a)").
+				AppendNamedArg("a", 1).
+				WithArgs(2),
+			wantErr: sqlf.ErrInvalidIndex,
+		},
+		{
+			name: "with fragments stale name",
+			fragment: sqlf.F("#f(a)").
+				AppendNamedFragment("a", sqlf.F("a")).
+				WithFragments(sqlf.F("b")),
+			wantErr: sqlf.ErrInvalidIndex,
+		},
+		{
+			name: "with fragments renamed",
+			fragment: sqlf.F("#f(b)").
+				AppendNamedFragment("a", sqlf.F("a")).
+				WithFragments().
+				AppendNamedFragment("b", sqlf.F("b")),
+			want: "b",
+		},
+		{
+			name: "unknown name",
+			fragment: sqlf.F("#f(foo)").
+				AppendNamedFragment("bar", sqlf.F("bar")),
+			wantErr: sqlf.ErrInvalidIndex,
+		},
+		{
+			name: "unused named",
+			fragment: sqlf.F("#f(foo)").
+				AppendNamedFragment("foo", sqlf.F("foo")).
+				AppendNamedFragment("bar", sqlf.F("bar")),
+			wantErr: &sqlf.UnusedError{},
+		},
+		{
+			name: "duplicate names",
+			fragment: sqlf.F("#f(foo)").
+				AppendNamedFragment("foo", sqlf.F("foo")).
+				AppendNamedFragment("foo", sqlf.F("bar")),
+			wantErr: errors.New("duplicate fragment name 'foo'"),
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			query, args, err := tc.fragment.BuildQuery(syntax.Dollar)
+			verr := sqlf.Validate(tc.fragment)
+			if tc.wantErr != nil {
+				for _, err := range []error{err, verr} {
+					if err == nil {
+						t.Fatal("want error, got nil")
+					}
+					if !matchError(err, tc.wantErr) {
+						t.Errorf("got error %v, want %v", err, tc.wantErr)
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if verr != nil {
+				t.Fatal(verr)
+			}
+			if query != tc.want {
+				t.Errorf("got %q, want %q", query, tc.want)
+			}
+			if !reflect.DeepEqual(args, tc.wantArgs) {
+				t.Errorf("got args %v, want %v", args, tc.wantArgs)
+			}
+		})
+	}
+}
+
+// matchError reports whether err matches want by errors.Is,
+// the type of a typed error, or the message of the cause.
+func matchError(err, want error) bool {
+	if errors.Is(err, want) {
+		return true
+	}
+	switch want.(type) {
+	case *sqlf.UnusedError:
+		var target *sqlf.UnusedError
+		return errors.As(err, &target)
+	}
+	var be *sqlf.BuildError
+	if errors.As(err, &be) {
+		return be.Err.Error() == want.Error()
+	}
+	return false
+}
+
+func ExampleFragment_AppendNamedFragment() {
+	query, args, err := sqlf.F("SELECT * FROM users WHERE #f('user_filter') AND age > #arg(min_age)").
+		AppendNamedFragment("user_filter", sqlf.Fa("name = $1", "alice")).
+		AppendNamedArg("min_age", 18).
+		BuildQuery(syntax.Dollar)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println(query)
+	fmt.Println(args)
+	// Output:
+	// SELECT * FROM users WHERE name = $1 AND age > $2
+	// [alice 18]
+}
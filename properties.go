@@ -1,7 +1,5 @@
 package sqlf
 
-import "fmt"
-
 // Properties is a list of properties.
 type Properties []Property
 
@@ -13,28 +11,38 @@ type Properties []Property
 // See examples for ContextWithFuncs() for how to use it.
 func (p Properties) Build(ctx *Context, i int) (string, error) {
 	if i < 1 || i > len(p) {
-		return "", fmt.Errorf("%w: %d", ErrInvalidIndex, i)
+		return "", &InvalidIndexError{Index: i}
 	}
 	return p[i-1].BuildFragment(ctx)
 }
 
-// checkUsage checks if all properties are used.
-func (p Properties) checkUsage() error {
+// unused returns the indexes of the unused properties,
+// except the optional ones.
+func (p Properties) unused() []int {
+	var r []int
 	for i, prop := range p {
+		if o, ok := prop.(interface{ Optional() bool }); ok && o.Optional() {
+			continue
+		}
 		if !prop.Used() {
-			return fmt.Errorf("#%d unused", i+1)
+			r = append(r, i+1)
 		}
 	}
-	return nil
+	return r
 }
 
 // NewFragmentProperties creates new properties from FragmentBuilder.
 // It's useful for creating global fragment properties shared between fragments,
 // see examples for ContextWithFuncs() for how to use it.
+//
+// The fragments wrapped by OptionalFragment are created as optional properties.
 func NewFragmentProperties(fragments ...FragmentBuilder) Properties {
 	r := make(Properties, 0)
 	for _, f := range fragments {
-		r = append(r, newDefaultProperty(f))
+		f, optional := fragmentValue(f)
+		p := newDefaultProperty(f)
+		p.optional = optional
+		r = append(r, p)
 	}
 	return r
 }
@@ -42,10 +50,15 @@ func NewFragmentProperties(fragments ...FragmentBuilder) Properties {
 // NewArgsProperties  creates new properties from args.
 // It's useful for creating global arg properties shared between fragments,
 // see examples for ContextWithFuncs() for how to use it.
+//
+// The args wrapped by OptionalArg are created as optional properties.
 func NewArgsProperties(args ...any) Properties {
 	r := make(Properties, 0)
 	for _, a := range args {
-		r = append(r, newDefaultProperty(&arg{a}))
+		a, optional := argValue(a)
+		p := newDefaultProperty(&arg{a})
+		p.optional = optional
+		r = append(r, p)
 	}
 	return r
 }
@@ -58,6 +71,9 @@ type arg struct {
 
 // BuildFragment implements FragmentBuilder
 func (c *arg) BuildFragment(ctx *Context) (query string, err error) {
+	if IsList(c.any) && ctx.expandSlices() {
+		return ctx.commitSlice(c.any)
+	}
 	built := ctx.CommitArg(c.any)
 	return built, nil
 }
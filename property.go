@@ -12,8 +12,9 @@ type Property interface {
 var _ Property = (*defaultProperty)(nil)
 
 type defaultProperty struct {
-	value FragmentBuilder
-	used  bool
+	value    FragmentBuilder
+	used     bool
+	optional bool
 }
 
 // newDefaultProperty returns a new property.
@@ -28,6 +29,11 @@ func (p *defaultProperty) ReportUsed() {
 	p.used = true
 }
 
+// Optional reports if the property is allowed to be unused.
+func (p *defaultProperty) Optional() bool {
+	return p.optional
+}
+
 // Used returns true if the column is used.
 func (p *defaultProperty) Used() bool {
 	return p.used
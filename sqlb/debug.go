@@ -0,0 +1,47 @@
+package sqlb
+
+import (
+	"log"
+	"sync"
+
+	"github.com/qjebbs/go-sqlf/v2"
+	"github.com/qjebbs/go-sqlf/v2/util"
+)
+
+var (
+	debugHookMu sync.RWMutex
+	debugHook   sqlf.BuildHook = logHook{}
+)
+
+// SetDebugHook sets the hook for the builders in debug mode, see
+// QueryBuilder.Debug. The default one prints the interpolated queries
+// with the standard logger. A nil hook disables the debug output.
+func SetDebugHook(h sqlf.BuildHook) {
+	debugHookMu.Lock()
+	defer debugHookMu.Unlock()
+	debugHook = h
+}
+
+// DebugHook returns the hook for the builders in debug mode.
+func DebugHook() sqlf.BuildHook {
+	debugHookMu.RLock()
+	defer debugHookMu.RUnlock()
+	return debugHook
+}
+
+// logHook prints the interpolated queries with the standard logger.
+type logHook struct{}
+
+func (logHook) OnBuildStart(*sqlf.BuildEvent) {}
+
+func (logHook) OnBuildEnd(e *sqlf.BuildEvent) {
+	if e.Err != nil {
+		log.Printf("debug: build query: %s\n", e.Err)
+		return
+	}
+	interpolated, err := util.Interpolate(e.Query, e.Args)
+	if err != nil {
+		log.Printf("debug: interpolated query: %s\n", err)
+	}
+	log.Println(interpolated)
+}
@@ -26,7 +26,8 @@ func NewUserQueryBuilder(db util.QueryAble) *UserQueryBuilder {
 		From(Users)
 	//  .InnerJoin(...).
 	// 	LeftJoin(...).
-	// 	LeftJoinOptional(...)
+	// 	LeftJoinOptional(...).
+	// 	WithSchema(schema) // joins the related tables on demand
 	return &UserQueryBuilder{db, b}
 }
 
@@ -0,0 +1,64 @@
+package expr
+
+import (
+	"strings"
+
+	"github.com/qjebbs/go-sqlf/v2/sqlb"
+)
+
+// CaseBuilder builds the CASE expression, see Case.
+type CaseBuilder struct {
+	operand  []any // the operand of the simple CASE, if any
+	whens    []any // the pairs of the conditions and results
+	elseExpr []any
+}
+
+// Case starts a searched CASE expression, or a simple one if the
+// operand is given, e.g.:
+//
+//	expr.Case().
+//		When(expr.Gt(t.Column("score"), 90), "A").
+//		When(expr.Gt(t.Column("score"), 60), "B").
+//		Else("C").
+//		End()
+//	// CASE WHEN t.score > $1 THEN $2 WHEN t.score > $3 THEN $4 ELSE $5 END
+//
+//	expr.Case(t.Column("status")).When(1, "active").End()
+//	// CASE t.status WHEN $1 THEN $2 END
+func Case(operand ...any) *CaseBuilder {
+	return &CaseBuilder{operand: operand}
+}
+
+// When adds a 'WHEN cond THEN result' branch, the cond is a condition
+// for the searched CASE, or a value for the simple one.
+func (c *CaseBuilder) When(cond, result any) *CaseBuilder {
+	c.whens = append(c.whens, cond, result)
+	return c
+}
+
+// Else sets the 'ELSE result' branch.
+func (c *CaseBuilder) Else(result any) *CaseBuilder {
+	c.elseExpr = []any{result}
+	return c
+}
+
+// End returns the CASE expression as a column.
+func (c *CaseBuilder) End() *sqlb.Column {
+	b := new(strings.Builder)
+	operands := make([]any, 0, len(c.operand)+len(c.whens)+len(c.elseExpr))
+	b.WriteString("CASE")
+	if len(c.operand) > 0 {
+		b.WriteString(" " + placeholder)
+		operands = append(operands, c.operand[0])
+	}
+	for i := 0; i < len(c.whens); i += 2 {
+		b.WriteString(" WHEN " + placeholder + " THEN " + placeholder)
+		operands = append(operands, c.whens[i], c.whens[i+1])
+	}
+	if len(c.elseExpr) > 0 {
+		b.WriteString(" ELSE " + placeholder)
+		operands = append(operands, c.elseExpr[0])
+	}
+	b.WriteString(" END")
+	return newColumn(b.String(), operands...)
+}
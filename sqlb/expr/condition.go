@@ -0,0 +1,179 @@
+package expr
+
+import (
+	"database/sql/driver"
+	"reflect"
+
+	"github.com/qjebbs/go-sqlf/v2"
+)
+
+// Eq returns the condition 'a = b', or 'a IS NULL' if b is NULL, see
+// isNull, e.g. a nil *string of a nullable field.
+func Eq(a, b any) *sqlf.Fragment {
+	if isNull(b) {
+		return IsNull(a)
+	}
+	return compare(a, "=", b)
+}
+
+// NotEq returns the condition 'a <> b', or 'a IS NOT NULL' if b is NULL,
+// see isNull.
+func NotEq(a, b any) *sqlf.Fragment {
+	if isNull(b) {
+		return IsNotNull(a)
+	}
+	return compare(a, "<>", b)
+}
+
+// Lt returns the condition 'a < b'.
+func Lt(a, b any) *sqlf.Fragment {
+	return compare(a, "<", b)
+}
+
+// Lte returns the condition 'a <= b'.
+func Lte(a, b any) *sqlf.Fragment {
+	return compare(a, "<=", b)
+}
+
+// Gt returns the condition 'a > b'.
+func Gt(a, b any) *sqlf.Fragment {
+	return compare(a, ">", b)
+}
+
+// Gte returns the condition 'a >= b'.
+func Gte(a, b any) *sqlf.Fragment {
+	return compare(a, ">=", b)
+}
+
+// IsNull returns the condition 'a IS NULL'.
+func IsNull(a any) *sqlf.Fragment {
+	return newFragment("{} IS NULL", a)
+}
+
+// IsNotNull returns the condition 'a IS NOT NULL'.
+func IsNotNull(a any) *sqlf.Fragment {
+	return newFragment("{} IS NOT NULL", a)
+}
+
+// IsDistinctFrom returns the condition 'a IS DISTINCT FROM b'.
+func IsDistinctFrom(a, b any) *sqlf.Fragment {
+	return compare(a, "IS DISTINCT FROM", b)
+}
+
+// IsNotDistinctFrom returns the condition 'a IS NOT DISTINCT FROM b'.
+func IsNotDistinctFrom(a, b any) *sqlf.Fragment {
+	return compare(a, "IS NOT DISTINCT FROM", b)
+}
+
+// Between returns the condition 'a BETWEEN low AND high'.
+func Between(a, low, high any) *sqlf.Fragment {
+	return newFragment("{} BETWEEN {} AND {}", a, low, high)
+}
+
+// NotBetween returns the condition 'a NOT BETWEEN low AND high'.
+func NotBetween(a, low, high any) *sqlf.Fragment {
+	return newFragment("{} NOT BETWEEN {} AND {}", a, low, high)
+}
+
+// Like returns the condition 'a LIKE pattern'.
+func Like(a, pattern any) *sqlf.Fragment {
+	return compare(a, "LIKE", pattern)
+}
+
+// NotLike returns the condition 'a NOT LIKE pattern'.
+func NotLike(a, pattern any) *sqlf.Fragment {
+	return compare(a, "NOT LIKE", pattern)
+}
+
+// ILike returns the condition 'a ILIKE pattern', which is PostgreSQL only.
+func ILike(a, pattern any) *sqlf.Fragment {
+	return compare(a, "ILIKE", pattern)
+}
+
+// NotILike returns the condition 'a NOT ILIKE pattern', which is PostgreSQL only.
+func NotILike(a, pattern any) *sqlf.Fragment {
+	return compare(a, "NOT ILIKE", pattern)
+}
+
+// And returns the conditions joined with AND in parentheses, the empty
+// ones are skipped, and it builds empty if all of them are empty.
+func And(conds ...sqlf.FragmentBuilder) *sqlf.Fragment {
+	return join(" AND ", conds)
+}
+
+// Or returns the conditions joined with OR in parentheses, the empty
+// ones are skipped, and it builds empty if all of them are empty.
+func Or(conds ...sqlf.FragmentBuilder) *sqlf.Fragment {
+	return join(" OR ", conds)
+}
+
+// Not returns the condition 'NOT (cond)', which builds empty if
+// cond builds empty.
+func Not(cond sqlf.FragmentBuilder) *sqlf.Fragment {
+	return sqlf.Ff("#f1", &wrapped{
+		prefix:  "NOT (",
+		suffix:  ")",
+		builder: cond,
+	})
+}
+
+func compare(a any, op string, b any) *sqlf.Fragment {
+	return newFragment("{} "+op+" {}", a, b)
+}
+
+func join(sep string, conds []sqlf.FragmentBuilder) *sqlf.Fragment {
+	return sqlf.Ff("#f1", &wrapped{
+		prefix:  "(",
+		suffix:  ")",
+		builder: sqlf.F("#join('#fragment', '" + sep + "')").WithFragments(conds...),
+	})
+}
+
+// isNull reports whether the operand is a NULL value rather than a
+// builder, e.g. nil, a nil pointer or an invalid sql.NullString.
+func isNull(v any) bool {
+	if v == nil {
+		return true
+	}
+	if _, ok := v.(sqlf.FragmentBuilder); ok {
+		return false
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Slice, reflect.Chan, reflect.Func, reflect.Interface:
+		if rv.IsNil() {
+			return true
+		}
+	}
+	if valuer, ok := v.(driver.Valuer); ok {
+		value, err := valuer.Value()
+		return err == nil && value == nil
+	}
+	return false
+}
+
+var (
+	_ sqlf.FragmentBuilder = (*wrapped)(nil)
+	_ sqlf.Parent          = (*wrapped)(nil)
+)
+
+// wrapped wraps the built builder with the prefix and suffix,
+// or builds empty if the builder builds empty.
+type wrapped struct {
+	prefix, suffix string
+	builder        sqlf.FragmentBuilder
+}
+
+// BuildFragment implements sqlf.FragmentBuilder
+func (w *wrapped) BuildFragment(ctx *sqlf.Context) (string, error) {
+	query, err := w.builder.BuildFragment(ctx)
+	if err != nil || query == "" {
+		return "", err
+	}
+	return w.prefix + query + w.suffix, nil
+}
+
+// Children implements sqlf.Parent
+func (w *wrapped) Children() []sqlf.FragmentBuilder {
+	return []sqlf.FragmentBuilder{w.builder}
+}
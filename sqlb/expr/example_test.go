@@ -0,0 +1,34 @@
+package expr_test
+
+import (
+	"fmt"
+
+	"github.com/qjebbs/go-sqlf/v2/sqlb"
+	"github.com/qjebbs/go-sqlf/v2/sqlb/expr"
+	"github.com/qjebbs/go-sqlf/v2/syntax"
+)
+
+func ExampleCase() {
+	users := sqlb.NewTableAliased("users", "u")
+	b := sqlb.NewQueryBuilder().
+		Select(
+			users.Column("id"),
+			expr.Case().
+				When(expr.Gte(users.Column("score"), 90), "A").
+				When(expr.Gte(users.Column("score"), 60), "B").
+				Else("C").
+				End().As("grade"),
+		).
+		From(users).
+		Where(expr.Eq(users.Column("deleted_at"), nil))
+	query, args, err := b.BuildQuery(syntax.Dollar)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println(query)
+	fmt.Println(args...)
+	// Output:
+	// SELECT u.id, CASE WHEN u.score >= $1 THEN $2 WHEN u.score >= $3 THEN $4 ELSE $5 END AS grade FROM users AS u WHERE u.deleted_at IS NULL
+	// 90 A 60 B C
+}
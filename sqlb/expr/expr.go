@@ -0,0 +1,65 @@
+// Package expr provides the expressions of common SQL operators,
+// functions and CASE for sqlb, which produce *sqlb.Column for the
+// select list, and *sqlf.Fragment for the conditions:
+//
+//	b.Select(
+//		users.Column("id"),
+//		expr.Coalesce(users.Column("nickname"), users.Column("name")).As("name"),
+//	).Where(expr.And(
+//		expr.Eq(users.Column("deleted_at"), nil),
+//		expr.Between(users.Column("age"), 18, 60),
+//	))
+//	// SELECT u.id, COALESCE(u.nickname, u.name) AS name ...
+//	// WHERE (u.deleted_at IS NULL AND u.age BETWEEN $1 AND $2)
+//
+// An operand of type sqlf.FragmentBuilder, e.g. *sqlb.Column or
+// *sqlf.Fragment, is referenced as a fragment, so that the tables
+// referenced are tracked by the *sqlb.QueryBuilder for the dependencies.
+// Any other operand is a value, which is referenced as an arg, e.g. the
+// string "u.name" is a value, not a column.
+package expr
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/qjebbs/go-sqlf/v2"
+	"github.com/qjebbs/go-sqlf/v2/sqlb"
+)
+
+// placeholder is replaced by the operands in the templates.
+const placeholder = "{}"
+
+// newFragment returns the fragment of the template, where each
+// placeholder is replaced by the reference of the next operand.
+func newFragment(tmpl string, operands ...any) *sqlf.Fragment {
+	f := &sqlf.Fragment{}
+	b := new(strings.Builder)
+	parts := strings.Split(tmpl, placeholder)
+	for i, part := range parts {
+		b.WriteString(part)
+		if i == len(parts)-1 {
+			break
+		}
+		switch v := operands[i].(type) {
+		case sqlf.FragmentBuilder:
+			f.Fragments = append(f.Fragments, v)
+			fmt.Fprintf(b, "#f%d", len(f.Fragments))
+		default:
+			f.Args = append(f.Args, v)
+			fmt.Fprintf(b, "$%d", len(f.Args))
+		}
+	}
+	f.Raw = b.String()
+	return f
+}
+
+// newColumn returns the column of the template, see newFragment.
+func newColumn(tmpl string, operands ...any) *sqlb.Column {
+	return sqlb.ExprColumn(newFragment(tmpl, operands...))
+}
+
+// list returns n placeholders separated by commas.
+func list(n int) string {
+	return strings.TrimSuffix(strings.Repeat(placeholder+", ", n), ", ")
+}
@@ -0,0 +1,221 @@
+package expr_test
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+
+	"github.com/qjebbs/go-sqlf/v2"
+	"github.com/qjebbs/go-sqlf/v2/sqlb"
+	"github.com/qjebbs/go-sqlf/v2/sqlb/expr"
+	"github.com/qjebbs/go-sqlf/v2/syntax"
+)
+
+func TestExpr(t *testing.T) {
+	t.Parallel()
+	u := sqlb.NewTableAliased("users", "u")
+	testCases := []struct {
+		name     string
+		fragment sqlf.FragmentBuilder
+		want     string
+		wantArgs []any
+	}{
+		{
+			name:     "eq",
+			fragment: expr.Eq(u.Column("id"), 1),
+			want:     "u.id = $1",
+			wantArgs: []any{1},
+		},
+		{
+			name:     "eq nil",
+			fragment: expr.Eq(u.Column("deleted_at"), nil),
+			want:     "u.deleted_at IS NULL",
+		},
+		{
+			name:     "eq nil pointer",
+			fragment: expr.Eq(u.Column("name"), (*string)(nil)),
+			want:     "u.name IS NULL",
+		},
+		{
+			name:     "eq null valuer",
+			fragment: expr.Eq(u.Column("name"), sql.NullString{}),
+			want:     "u.name IS NULL",
+		},
+		{
+			name:     "eq valid valuer",
+			fragment: expr.Eq(u.Column("name"), sql.NullString{String: "a", Valid: true}),
+			want:     "u.name = $1",
+			wantArgs: []any{sql.NullString{String: "a", Valid: true}},
+		},
+		{
+			name:     "not eq nil",
+			fragment: expr.NotEq(u.Column("deleted_at"), nil),
+			want:     "u.deleted_at IS NOT NULL",
+		},
+		{
+			name:     "compare columns",
+			fragment: expr.Gte(u.Column("updated_at"), u.Column("created_at")),
+			want:     "u.updated_at >= u.created_at",
+		},
+		{
+			name:     "between",
+			fragment: expr.NotBetween(u.Column("age"), 18, 60),
+			want:     "u.age NOT BETWEEN $1 AND $2",
+			wantArgs: []any{18, 60},
+		},
+		{
+			name:     "like",
+			fragment: expr.ILike(u.Column("name"), "a%"),
+			want:     "u.name ILIKE $1",
+			wantArgs: []any{"a%"},
+		},
+		{
+			name:     "distinct from",
+			fragment: expr.IsDistinctFrom(u.Column("a"), u.Column("b")),
+			want:     "u.a IS DISTINCT FROM u.b",
+		},
+		{
+			name: "and or not",
+			fragment: expr.And(
+				expr.Lt(u.Column("a"), 1),
+				expr.Or(expr.Gt(u.Column("b"), 2), expr.Not(expr.Eq(u.Column("c"), 3))),
+			),
+			want:     "(u.a < $1 AND (u.b > $2 OR NOT (u.c = $3)))",
+			wantArgs: []any{1, 2, 3},
+		},
+		{
+			name:     "and skips empty",
+			fragment: expr.And(sqlf.F(""), expr.Eq(u.Column("a"), 1), expr.Or()),
+			want:     "(u.a = $1)",
+			wantArgs: []any{1},
+		},
+		{
+			name:     "empty and",
+			fragment: expr.And(expr.Or()),
+			want:     "",
+		},
+		{
+			name:     "not empty",
+			fragment: expr.Not(expr.And()),
+			want:     "",
+		},
+		{
+			name:     "arithmetic",
+			fragment: expr.Mul(expr.Add(u.Column("a"), 1), expr.Mod(u.Column("b"), 2)),
+			want:     "((u.a + $1) * (u.b % $2))",
+			wantArgs: []any{1, 2},
+		},
+		{
+			name:     "coalesce",
+			fragment: expr.Coalesce(u.Column("nickname"), u.Column("name"), ""),
+			want:     "COALESCE(u.nickname, u.name, $1)",
+			wantArgs: []any{""},
+		},
+		{
+			name:     "cast",
+			fragment: expr.Cast(u.Column("price"), "NUMERIC(10, 2)"),
+			want:     "CAST(u.price AS NUMERIC(10, 2))",
+		},
+		{
+			name:     "aggregates",
+			fragment: sqlf.Ff("#join('#fragment', ', ')", expr.CountAll(), expr.CountDistinct(u.Column("a")), expr.Sum(u.Column("b")), expr.Max(u.Column("c"))),
+			want:     "COUNT(*), COUNT(DISTINCT u.a), SUM(u.b), MAX(u.c)",
+		},
+		{
+			name: "searched case",
+			fragment: expr.Case().
+				When(expr.Gt(u.Column("score"), 90), "A").
+				When(expr.Gt(u.Column("score"), 60), "B").
+				Else("C").
+				End(),
+			want:     "CASE WHEN u.score > $1 THEN $2 WHEN u.score > $3 THEN $4 ELSE $5 END",
+			wantArgs: []any{90, "A", 60, "B", "C"},
+		},
+		{
+			name:     "simple case",
+			fragment: expr.Case(u.Column("status")).When(1, u.Column("name")).End(),
+			want:     "CASE u.status WHEN $1 THEN u.name END",
+			wantArgs: []any{1},
+		},
+	}
+	for _, tc := range testCases {
+		got, args, err := sqlf.BuildQuery(tc.fragment, syntax.Dollar)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if got != tc.want {
+			t.Errorf("%s: got:\n%s\nwant:\n%s", tc.name, got, tc.want)
+		}
+		if len(args) != 0 || len(tc.wantArgs) != 0 {
+			if !reflect.DeepEqual(args, tc.wantArgs) {
+				t.Errorf("%s: got args %v, want %v", tc.name, args, tc.wantArgs)
+			}
+		}
+	}
+}
+
+func TestExprQueryBuilder(t *testing.T) {
+	t.Parallel()
+	var (
+		users    = sqlb.NewTableAliased("users", "u")
+		profiles = sqlb.NewTableAliased("profiles", "p")
+		emails   = sqlb.NewTableAliased("emails", "e")
+	)
+	b := sqlb.NewQueryBuilder().
+		Select(
+			users.Column("id"),
+			expr.Coalesce(emails.Column("address"), "").As("email"),
+		).
+		From(users).
+		LeftJoin(emails, sqlf.Ff("#f1=#f2", emails.Column("user_id"), users.Column("id")), sqlb.ToOne()).
+		LeftJoin(profiles, sqlf.Ff("#f1=#f2", profiles.Column("user_id"), users.Column("id")), sqlb.ToOne()).
+		Where(expr.And(
+			expr.Eq(users.Column("deleted_at"), nil),
+			expr.Between(users.Column("age"), 18, 60),
+		))
+	got, args, err := b.BuildQuery(syntax.Dollar)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the unreferenced profiles is eliminated as a to-one join, while emails is kept
+	want := "SELECT u.id, COALESCE(e.address, $3) AS email FROM users AS u " +
+		"LEFT JOIN emails AS e ON e.user_id=u.id " +
+		"WHERE (u.deleted_at IS NULL AND u.age BETWEEN $1 AND $2)"
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+	wantArgs := []any{18, 60, ""}
+	if !reflect.DeepEqual(args, wantArgs) {
+		t.Errorf("got args %v, want %v", args, wantArgs)
+	}
+}
+
+// countBuilder counts the builds of the fragment.
+type countBuilder struct {
+	*sqlf.Fragment
+	n int
+}
+
+func (b *countBuilder) BuildFragment(ctx *sqlf.Context) (string, error) {
+	b.n++
+	return b.Fragment.BuildFragment(ctx)
+}
+
+func TestNestedConditionsBuildOnce(t *testing.T) {
+	t.Parallel()
+	leaf := &countBuilder{Fragment: sqlf.Fa("a = $1", 1)}
+	var cond sqlf.FragmentBuilder = leaf
+	for i := 0; i < 10; i++ {
+		cond = expr.And(cond)
+	}
+	got, _, err := sqlf.BuildQuery(cond, syntax.Dollar)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "((((((((((a = $1))))))))))"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	if leaf.n != 1 {
+		t.Errorf("the leaf is built %d times, want 1", leaf.n)
+	}
+}
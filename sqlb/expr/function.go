@@ -0,0 +1,86 @@
+package expr
+
+import (
+	"github.com/qjebbs/go-sqlf/v2/sqlb"
+)
+
+// Add returns the column '(a + b)'.
+func Add(a, b any) *sqlb.Column {
+	return arithmetic(a, "+", b)
+}
+
+// Sub returns the column '(a - b)'.
+func Sub(a, b any) *sqlb.Column {
+	return arithmetic(a, "-", b)
+}
+
+// Mul returns the column '(a * b)'.
+func Mul(a, b any) *sqlb.Column {
+	return arithmetic(a, "*", b)
+}
+
+// Div returns the column '(a / b)'.
+func Div(a, b any) *sqlb.Column {
+	return arithmetic(a, "/", b)
+}
+
+// Mod returns the column '(a % b)'.
+func Mod(a, b any) *sqlb.Column {
+	return arithmetic(a, "%", b)
+}
+
+// Coalesce returns the column 'COALESCE(values...)'.
+func Coalesce(values ...any) *sqlb.Column {
+	return Func("COALESCE", values...)
+}
+
+// Cast returns the column 'CAST(v AS typ)', e.g.:
+//
+//	expr.Cast(t.Column("price"), "NUMERIC(10, 2)")
+func Cast(v any, typ string) *sqlb.Column {
+	return newColumn("CAST({} AS "+typ+")", v)
+}
+
+// Func returns the column of the function call 'name(args...)'.
+func Func(name string, args ...any) *sqlb.Column {
+	return newColumn(name+"("+list(len(args))+")", args...)
+}
+
+// Count returns the column 'COUNT(v)'.
+func Count(v any) *sqlb.Column {
+	return Func("COUNT", v)
+}
+
+// CountAll returns the column 'COUNT(*)'.
+func CountAll() *sqlb.Column {
+	return newColumn("COUNT(*)")
+}
+
+// CountDistinct returns the column 'COUNT(DISTINCT v)'.
+func CountDistinct(v any) *sqlb.Column {
+	return newColumn("COUNT(DISTINCT {})", v)
+}
+
+// Sum returns the column 'SUM(v)'.
+func Sum(v any) *sqlb.Column {
+	return Func("SUM", v)
+}
+
+// Avg returns the column 'AVG(v)'.
+func Avg(v any) *sqlb.Column {
+	return Func("AVG", v)
+}
+
+// Min returns the column 'MIN(v)'.
+func Min(v any) *sqlb.Column {
+	return Func("MIN", v)
+}
+
+// Max returns the column 'MAX(v)'.
+func Max(v any) *sqlb.Column {
+	return Func("MAX", v)
+}
+
+func arithmetic(a any, op string, b any) *sqlb.Column {
+	return newColumn("({} "+op+" {})", a, b)
+}
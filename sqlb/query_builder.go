@@ -24,6 +24,8 @@ type QueryBuilder struct {
 	offset     int64                  // offset count
 	unions     []sqlf.FragmentBuilder // union queries
 
+	schema *Schema // the schema to infer joins
+
 	errors []error // errors during building
 
 	debug bool // debug mode
@@ -33,6 +35,9 @@ type fromTable struct {
 	Names    TableAliased
 	Fragment *sqlf.Fragment
 	Optional bool
+	Join     string // the join keywords, empty for the main table
+
+	joinOptions
 }
 
 // NewQueryBuilder returns a new QueryBuilder.
@@ -62,6 +67,41 @@ func (b *QueryBuilder) Select(columns ...*Column) *QueryBuilder {
 	return b
 }
 
+// AddSelect appends the columns to the SELECT clause.
+func (b *QueryBuilder) AddSelect(columns ...*Column) *QueryBuilder {
+	b.selects.AppendFragments(convertFragmentBuilders(columns)...)
+	return b
+}
+
+// SelectExpr appends an expression with args to the SELECT clause,
+// the args are referenced by the expression like $1 or ?, e.g.:
+//
+//	b.SelectExpr("COALESCE(u.nickname, $1) AS nickname", "anonymous")
+//
+// To reference the expression by alias, use ExprColumn and Column.As
+// with AddSelect instead.
+func (b *QueryBuilder) SelectExpr(expr string, args ...any) *QueryBuilder {
+	return b.AddSelect(ExprColumn(sqlf.Fa(expr, args...)))
+}
+
+// RemoveSelect removes the columns aliased by Column.As from the SELECT
+// clause, by the aliases.
+func (b *QueryBuilder) RemoveSelect(aliases ...string) *QueryBuilder {
+	remove := make(map[string]bool, len(aliases))
+	for _, a := range aliases {
+		remove[a] = true
+	}
+	kept := b.selects.Fragments[:0:0]
+	for _, f := range b.selects.Fragments {
+		if c, ok := f.(*Column); ok && c.alias != "" && remove[c.alias] {
+			continue
+		}
+		kept = append(kept, f)
+	}
+	b.selects.Fragments = kept
+	return b
+}
+
 // Limit set the limit.
 func (b *QueryBuilder) Limit(limit int64) *QueryBuilder {
 	if limit > 0 {
@@ -93,3 +133,27 @@ func (b *QueryBuilder) Union(builders ...sqlf.FragmentBuilder) *QueryBuilder {
 	b.unions = append(b.unions, builders...)
 	return b
 }
+
+var _ sqlf.Parent = (*QueryBuilder)(nil)
+
+// Children implements sqlf.Parent
+func (b *QueryBuilder) Children() []sqlf.FragmentBuilder {
+	if b == nil {
+		return nil
+	}
+	r := make([]sqlf.FragmentBuilder, 0)
+	for _, cte := range b.ctes {
+		r = append(r, cte.FragmentBuilder)
+	}
+	for _, t := range b.tables {
+		if t.Fragment != nil {
+			r = append(r, t.Fragment)
+		}
+	}
+	r = append(r, b.selects, b.touches, b.conditions, b.groupbys)
+	for _, order := range b.orders {
+		r = append(r, order.column)
+	}
+	r = append(r, b.unions...)
+	return r
+}
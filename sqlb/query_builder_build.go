@@ -2,12 +2,10 @@ package sqlb
 
 import (
 	"fmt"
-	"log"
 	"strings"
 
 	"github.com/qjebbs/go-sqlf/v2"
 	"github.com/qjebbs/go-sqlf/v2/syntax"
-	"github.com/qjebbs/go-sqlf/v2/util"
 )
 
 var _ sqlf.QueryBuilder = (*QueryBuilder)(nil)
@@ -15,13 +13,11 @@ var _ sqlf.FragmentBuilder = (*QueryBuilder)(nil)
 
 // BuildQuery builds the query.
 func (b *QueryBuilder) BuildQuery(bindVarStyle syntax.BindVarStyle) (query string, args []any, err error) {
-	ctx := sqlf.NewContext(bindVarStyle)
-	query, err = b.buildInternal(ctx)
-	if err != nil {
-		return "", nil, err
+	if b.debug {
+		hook := sqlf.JoinBuildHooks(sqlf.GlobalBuildHook(), DebugHook())
+		return sqlf.BuildQuery(b, bindVarStyle, sqlf.WithBuildHook(hook))
 	}
-	args = ctx.Args()
-	return query, args, nil
+	return sqlf.BuildQuery(b, bindVarStyle)
 }
 
 // BuildFragment implements FragmentBuilder
@@ -29,7 +25,8 @@ func (b *QueryBuilder) BuildFragment(ctx *sqlf.Context) (query string, err error
 	return b.buildInternal(ctx)
 }
 
-// Debug enables debug mode.
+// Debug enables debug mode, which reports the queries built by
+// BuildQuery to the DebugHook, in addition to the global BuildHook.
 func (b *QueryBuilder) Debug() {
 	b.debug = true
 }
@@ -42,12 +39,21 @@ func (b *QueryBuilder) buildInternal(ctx *sqlf.Context) (string, error) {
 	if err := b.anyError(); err != nil {
 		return "", err
 	}
+	if b.schema != nil {
+		inferred, err := b.inferJoins(ctx)
+		if err != nil {
+			return "", err
+		}
+		b = inferred
+	}
 	clauses := make([]string, 0)
 
-	dep, err := b.collectDependencies()
+	dep, err := b.collectDependencies(ctx)
 	if err != nil {
 		return "", err
 	}
+	// the clauses but CTEs and unions can be correlated by nested queries
+	scoped := b.contextWithScope(ctx)
 
 	sq, err := b.buildCTEs(ctx, dep)
 	if err != nil {
@@ -60,28 +66,28 @@ func (b *QueryBuilder) buildInternal(ctx *sqlf.Context) (string, error) {
 	// reserve a position for select
 	selectAt := len(clauses)
 	clauses = append(clauses, "")
-	from, err := b.buildFrom(ctx, dep)
+	from, err := b.buildFrom(scoped, dep)
 	if err != nil {
 		return "", err
 	}
 	if from != "" {
 		clauses = append(clauses, from)
 	}
-	where, err := b.conditions.BuildFragment(ctx)
+	where, err := b.conditions.BuildFragment(scoped)
 	if err != nil {
 		return "", err
 	}
 	if where != "" {
 		clauses = append(clauses, where)
 	}
-	groupby, err := b.groupbys.BuildFragment(ctx)
+	groupby, err := b.groupbys.BuildFragment(scoped)
 	if err != nil {
 		return "", err
 	}
 	if groupby != "" {
 		clauses = append(clauses, groupby)
 	}
-	order, err := b.buildOrders(ctx)
+	order, orderTouches, err := b.buildOrders(scoped)
 	if err != nil {
 		return "", err
 	}
@@ -94,8 +100,8 @@ func (b *QueryBuilder) buildInternal(ctx *sqlf.Context) (string, error) {
 	if b.offset > 0 {
 		clauses = append(clauses, fmt.Sprintf(`OFFSET %d`, b.offset))
 	}
-	// select must build order, because buildOrders may add columns to touches
-	sel, err := b.buildSelects(ctx)
+	// select must build after order, to keep the order of args
+	sel, err := b.buildSelects(scoped, orderTouches)
 	if err != nil {
 		return "", err
 	}
@@ -108,13 +114,6 @@ func (b *QueryBuilder) buildInternal(ctx *sqlf.Context) (string, error) {
 		}
 		query = strings.TrimSpace(query + " " + union)
 	}
-	if b.debug {
-		interpolated, err := util.Interpolate(query, ctx.Args())
-		if err != nil {
-			log.Printf("debug: interpolated query: %s\n", err)
-		}
-		log.Println(interpolated)
-	}
 	return query, nil
 }
 
@@ -145,17 +144,23 @@ func (b *QueryBuilder) buildCTEs(ctx *sqlf.Context, dep map[TableAliased]bool) (
 	return "With " + strings.Join(clauses, ", "), nil
 }
 
-func (b *QueryBuilder) buildSelects(ctx *sqlf.Context) (string, error) {
+// buildSelects builds the SELECT clause, with the extra columns
+// to select and drop in scanning.
+func (b *QueryBuilder) buildSelects(ctx *sqlf.Context, extra []sqlf.FragmentBuilder) (string, error) {
+	selects := cloneFragment(b.selects)
+	for i, f := range selects.Fragments {
+		if c, ok := f.(*Column); ok && c.alias != "" && !c.ref {
+			selects.Fragments[i] = selectColumn{c}
+		}
+	}
 	if b.distinct {
-		b.selects.Prefix = "SELECT DISTINCT"
-	} else {
-		b.selects.Prefix = "SELECT"
+		selects.Prefix = "SELECT DISTINCT"
 	}
-	sel, err := b.selects.BuildFragment(ctx)
+	sel, err := selects.BuildFragment(ctx)
 	if err != nil {
 		return "", err
 	}
-	touches, err := b.touches.BuildFragment(ctx)
+	touches, err := cloneFragment(b.touches).AppendFragments(extra...).BuildFragment(ctx)
 	if err != nil {
 		return "", err
 	}
@@ -171,7 +176,7 @@ func (b *QueryBuilder) buildSelects(ctx *sqlf.Context) (string, error) {
 func (b *QueryBuilder) buildFrom(ctx *sqlf.Context, dep map[TableAliased]bool) (string, error) {
 	tables := make([]string, 0, len(b.tables))
 	for _, t := range b.tables {
-		if b.distinct && t.Optional && !dep[t.Names] {
+		if t.eliminable(b.distinct) && !dep[t.Names] {
 			continue
 		}
 		c, err := t.Fragment.BuildFragment(ctx)
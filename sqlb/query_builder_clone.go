@@ -0,0 +1,89 @@
+package sqlb
+
+import (
+	"github.com/qjebbs/go-sqlf/v2"
+)
+
+// Clone returns a deep copy of b, which can be modified and built
+// independently, e.g. to branch a base query per request:
+//
+//	base := sqlb.NewQueryBuilder().Select(...).From(...)
+//	q := base.Clone().Where(...)
+//
+// The fragments added by users are shared, since they are never changed
+// by the builder. The nested *QueryBuilder of CTEs and unions are cloned.
+func (b *QueryBuilder) Clone() *QueryBuilder {
+	if b == nil {
+		return nil
+	}
+	r := &QueryBuilder{
+		ctes:       make([]*cte, 0, len(b.ctes)),
+		ctesDict:   make(map[Table]*cte, len(b.ctesDict)),
+		tables:     make([]*fromTable, 0, len(b.tables)),
+		tablesDict: make(map[Table]*fromTable, len(b.tablesDict)),
+		selects:    cloneFragment(b.selects),
+		touches:    cloneFragment(b.touches),
+		conditions: cloneFragment(b.conditions),
+		orders:     make([]*orderItem, 0, len(b.orders)),
+		groupbys:   cloneFragment(b.groupbys),
+		distinct:   b.distinct,
+		limit:      b.limit,
+		offset:     b.offset,
+		unions:     make([]sqlf.FragmentBuilder, 0, len(b.unions)),
+		schema:     b.schema,
+		errors:     append([]error(nil), b.errors...),
+		debug:      b.debug,
+	}
+	ctes := make(map[*cte]*cte, len(b.ctes))
+	for _, c := range b.ctes {
+		clone := &cte{
+			name:            c.name,
+			deps:            append([]Table(nil), c.deps...),
+			FragmentBuilder: cloneBuilder(c.FragmentBuilder),
+		}
+		ctes[c] = clone
+		r.ctes = append(r.ctes, clone)
+	}
+	for name, c := range b.ctesDict {
+		r.ctesDict[name] = ctes[c]
+	}
+	tables := make(map[*fromTable]*fromTable, len(b.tables))
+	for _, t := range b.tables {
+		clone := *t
+		tables[t] = &clone
+		r.tables = append(r.tables, &clone)
+	}
+	for name, t := range b.tablesDict {
+		r.tablesDict[name] = tables[t]
+	}
+	for _, o := range b.orders {
+		clone := *o
+		r.orders = append(r.orders, &clone)
+	}
+	for _, u := range b.unions {
+		r.unions = append(r.unions, cloneBuilder(u))
+	}
+	return r
+}
+
+// cloneBuilder clones the builder if it's a *QueryBuilder.
+func cloneBuilder(b sqlf.FragmentBuilder) sqlf.FragmentBuilder {
+	if q, ok := b.(*QueryBuilder); ok {
+		return q.Clone()
+	}
+	return b
+}
+
+// cloneFragment returns a copy of f, whose slices can be appended
+// without changing f.
+func cloneFragment(f *sqlf.Fragment) *sqlf.Fragment {
+	if f == nil {
+		return nil
+	}
+	r := *f
+	r.Args = append([]any(nil), f.Args...)
+	r.Fragments = append([]sqlf.FragmentBuilder(nil), f.Fragments...)
+	r.ArgNames = append([]string(nil), f.ArgNames...)
+	r.FragmentNames = append([]string(nil), f.FragmentNames...)
+	return &r
+}
@@ -6,8 +6,9 @@ import (
 	"github.com/qjebbs/go-sqlf/v2"
 )
 
-// collectDependencies collects the dependencies of the tables.
-func (b *QueryBuilder) collectDependencies() (map[TableAliased]bool, error) {
+// referenceBuilders returns the builders referencing the tables, which
+// are the selects, conditions, group bys and orders.
+func (b *QueryBuilder) referenceBuilders() []sqlf.FragmentBuilder {
 	builders := []sqlf.FragmentBuilder{
 		b.selects,
 		b.touches,
@@ -17,20 +18,49 @@ func (b *QueryBuilder) collectDependencies() (map[TableAliased]bool, error) {
 	for _, order := range b.orders {
 		builders = append(builders, order.column)
 	}
+	return builders
+}
+
+// referencedTables returns the tables referenced by the selects,
+// conditions, group bys and orders, and the ones of them referenced
+// only by the nested queries.
+func (b *QueryBuilder) referencedTables() (tables []Table, nested map[Table]bool) {
+	c := collectTables(b.referenceBuilders()...)
+	return c.tables, c.nested
+}
 
-	tables := extractTables(builders...)
+// collectDependencies collects the dependencies of the tables, the
+// tables of the outer queries in ctx are resolved by them.
+func (b *QueryBuilder) collectDependencies(ctx *sqlf.Context) (map[TableAliased]bool, error) {
+	tables, nested := b.referencedTables()
+	outer := scopeOf(ctx)
 	deps := make(map[TableAliased]bool)
 	// first table is the main table and always included
 	deps[b.tables[0].Names] = true
 	for _, table := range tables {
+		if _, ok := b.tablesDict[table]; !ok && (nested[table] || outer.has(table)) {
+			// resolved by the outer queries, or reported by the
+			// nested query if it's undefined there.
+			continue
+		}
 		err := b.collectDepsFromTable(deps, table)
 		if err != nil {
 			return nil, err
 		}
 	}
+	// the tables not eliminable are always built, so are the tables
+	// their ON clauses reference.
+	for _, t := range b.tables {
+		if t.eliminable(b.distinct) {
+			continue
+		}
+		if err := b.collectDepsFromTable(deps, t.Names.AppliedName()); err != nil {
+			return nil, err
+		}
+	}
 	// mark for CTEs
 	for _, t := range b.tables {
-		if b.distinct && t.Optional && !deps[t.Names] {
+		if t.eliminable(b.distinct) && !deps[t.Names] {
 			continue
 		}
 		if cte, ok := b.ctesDict[t.Names.Name]; ok {
@@ -78,48 +108,61 @@ func (b *QueryBuilder) collectDepsFromTable(dep map[TableAliased]bool, t Table)
 	return nil
 }
 
-func extractTables(fragments ...sqlf.FragmentBuilder) []Table {
-	tables := []Table{}
-	dict := map[Table]bool{}
-	extractTables2(fragments, &tables, dict)
-	return tables
+func extractTables(builders ...sqlf.FragmentBuilder) []Table {
+	return collectTables(builders...).tables
 }
 
-func extractTables2(fragments []sqlf.FragmentBuilder, tables *[]Table, dict map[Table]bool) {
-	for _, f := range fragments {
-		if f == nil {
-			continue
-		}
-		if fragment, ok := f.(*sqlf.Fragment); ok {
-			extractTables2(fragment.Fragments, tables, dict)
-			continue
-		}
-		if column, ok := f.(*Column); ok && column != nil {
-			if column.table != "" {
-				if !dict[column.table] {
-					collectTable(column.table, tables, dict)
-				}
-			} else {
-				extractTables2(column.fragment.Fragments, tables, dict)
-			}
+func collectTables(builders ...sqlf.FragmentBuilder) *tableCollector {
+	c := &tableCollector{
+		dict:   make(map[Table]bool),
+		nested: make(map[Table]bool),
+	}
+	for _, b := range builders {
+		if b == nil {
 			continue
 		}
+		sqlf.Walk(b, c)
+	}
+	return c
+}
 
-		if table, ok := f.(Table); ok {
-			collectTable(table, tables, dict)
-			continue
-		}
+var _ sqlf.Visitor = (*tableCollector)(nil)
+
+// tableCollector collects the tables referenced in a fragment tree.
+type tableCollector struct {
+	tables []Table
+	dict   map[Table]bool
+	nested map[Table]bool // referenced only by the nested queries
+}
 
-		if table, ok := f.(TableAliased); ok {
-			collectTable(table.AppliedName(), tables, dict)
+// Visit implements sqlf.Visitor
+func (c *tableCollector) Visit(b sqlf.FragmentBuilder) sqlf.Visitor {
+	switch b := b.(type) {
+	case nestedQuery:
+		// a nested query references its own tables, and the ones
+		// of the outer queries if it's correlated.
+		for _, t := range b.queryBuilder().outerTables() {
+			c.collect(t, true)
 		}
+		return nil
+	case Table:
+		c.collect(b, false)
+	case TableAliased:
+		c.collect(b.AppliedName(), false)
 	}
+	return c
 }
 
-func collectTable(t Table, tables *[]Table, dict map[Table]bool) {
-	if dict[t] {
+func (c *tableCollector) collect(t Table, nested bool) {
+	if c.dict[t] {
+		if !nested {
+			delete(c.nested, t)
+		}
 		return
 	}
-	*tables = append(*tables, t)
-	dict[t] = true
+	c.tables = append(c.tables, t)
+	c.dict[t] = true
+	if nested {
+		c.nested[t] = true
+	}
 }
@@ -31,13 +31,19 @@ func (b *QueryBuilder) From(t TableAliased) *QueryBuilder {
 }
 
 // InnerJoin append a inner join table.
-func (b *QueryBuilder) InnerJoin(t TableAliased, on *sqlf.Fragment) *QueryBuilder {
-	return b.join("INNER JOIN", t, on, false)
+//
+// An inner join declared with ToOne() and Guaranteed() is eliminated if
+// not referenced, see JoinOption.
+func (b *QueryBuilder) InnerJoin(t TableAliased, on *sqlf.Fragment, options ...JoinOption) *QueryBuilder {
+	return b.join("INNER JOIN", t, on, false, options...)
 }
 
 // LeftJoin append / replace a left join table.
-func (b *QueryBuilder) LeftJoin(t TableAliased, on *sqlf.Fragment) *QueryBuilder {
-	return b.join("LEFT JOIN", t, on, false)
+//
+// A left join declared with ToOne() is eliminated if not referenced,
+// see JoinOption.
+func (b *QueryBuilder) LeftJoin(t TableAliased, on *sqlf.Fragment, options ...JoinOption) *QueryBuilder {
+	return b.join("LEFT JOIN", t, on, false, options...)
 }
 
 // LeftJoinOptional append / replace a left join table, and mark it as optional.
@@ -58,8 +64,11 @@ func (b *QueryBuilder) LeftJoin(t TableAliased, on *sqlf.Fragment) *QueryBuilder
 // They return the same result, but the second query more efficient.
 // If the join to "bar" is declared with LeftJoinOptional(), *QueryBuilder
 // will trim it if no relative columns referenced in the query, aka Join Elimination.
-func (b *QueryBuilder) LeftJoinOptional(t TableAliased, on *sqlf.Fragment) *QueryBuilder {
-	return b.join("LEFT JOIN", t, on, true)
+//
+// To eliminate the joins without SELECT DISTINCT, declare them as ToOne(),
+// see JoinOption.
+func (b *QueryBuilder) LeftJoinOptional(t TableAliased, on *sqlf.Fragment, options ...JoinOption) *QueryBuilder {
+	return b.join("LEFT JOIN", t, on, true, options...)
 }
 
 // RightJoin append / replace a right join table.
@@ -78,7 +87,7 @@ func (b *QueryBuilder) CrossJoin(t TableAliased) *QueryBuilder {
 }
 
 // join append or replace a join table.
-func (b *QueryBuilder) join(joinStr string, t TableAliased, on *sqlf.Fragment, optional bool) *QueryBuilder {
+func (b *QueryBuilder) join(joinStr string, t TableAliased, on *sqlf.Fragment, optional bool, options ...JoinOption) *QueryBuilder {
 	if t.Name == "" {
 		b.pushError(fmt.Errorf("join table name is empty"))
 		return b
@@ -106,6 +115,10 @@ func (b *QueryBuilder) join(joinStr string, t TableAliased, on *sqlf.Fragment, o
 			on.WithPrefix("ON"),
 		),
 		Optional: optional,
+		Join:     joinStr,
+	}
+	for _, opt := range options {
+		opt(&table.joinOptions)
 	}
 	if target, replacing := b.tablesDict[t.AppliedName()]; replacing {
 		*target = *table
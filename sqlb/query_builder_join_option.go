@@ -0,0 +1,72 @@
+package sqlb
+
+// JoinOption declares the facts of a join, which allows the
+// *QueryBuilder to eliminate it when none of its columns is referenced,
+// without SELECT DISTINCT:
+//
+//   - LEFT JOIN with ToOne(): each row matches at most one row of the
+//     joined table, e.g. joined on its primary key, so the join never
+//     changes the rows.
+//   - INNER JOIN with ToOne() and Guaranteed(): each row matches exactly
+//     one row, e.g. joined on a NOT NULL foreign key.
+//
+// With SELECT DISTINCT, the unreferenced LEFT JOIN declared with any
+// cardinality, and the INNER JOIN with Guaranteed(), are eliminated too,
+// since the duplicated rows are removed anyway.
+//
+// Like LeftJoinOptional, make sure the columns referenced by the query
+// are reflected in the fragments, so that the dependencies are correct.
+type JoinOption func(*joinOptions)
+
+type cardinality int
+
+const (
+	cardinalityUnknown cardinality = iota
+	cardinalityToOne
+	cardinalityToMany
+)
+
+type joinOptions struct {
+	cardinality cardinality
+	guaranteed  bool
+}
+
+// ToOne declares that each row matches at most one row of the joined table.
+func ToOne() JoinOption {
+	return func(o *joinOptions) {
+		o.cardinality = cardinalityToOne
+	}
+}
+
+// ToMany declares that each row may match many rows of the joined table.
+func ToMany() JoinOption {
+	return func(o *joinOptions) {
+		o.cardinality = cardinalityToMany
+	}
+}
+
+// Guaranteed declares that each row matches at least one row of the
+// joined table, e.g. by a NOT NULL foreign key. It applies to INNER JOIN.
+func Guaranteed() JoinOption {
+	return func(o *joinOptions) {
+		o.guaranteed = true
+	}
+}
+
+// eliminable reports whether the table can be eliminated from the query
+// when it's not referenced.
+func (t *fromTable) eliminable(distinct bool) bool {
+	switch t.Join {
+	case "LEFT JOIN":
+		if t.cardinality == cardinalityToOne {
+			return true
+		}
+		return distinct && (t.Optional || t.cardinality == cardinalityToMany)
+	case "INNER JOIN":
+		if !t.guaranteed {
+			return false
+		}
+		return t.cardinality == cardinalityToOne || distinct
+	}
+	return false
+}
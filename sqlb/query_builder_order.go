@@ -35,18 +35,21 @@ type orderItem struct {
 
 // OrderBy set the sorting order. the order can be "ASC", "DESC", "ASC NULLS FIRST" or "DESC NULLS LAST"
 func (b *QueryBuilder) OrderBy(column *Column, order Order) *QueryBuilder {
+	if order > DescNullsLast {
+		b.pushError(fmt.Errorf("invalid order: %d", order))
+		return b
+	}
 	b.orders = append(b.orders, &orderItem{column: column, order: order})
 	return b
 }
 
-func (b *QueryBuilder) buildOrders(ctx *sqlf.Context) (string, error) {
+// buildOrders builds the ORDER BY clause, and returns the columns to
+// select for SELECT DISTINCT, without changing the builder.
+func (b *QueryBuilder) buildOrders(ctx *sqlf.Context) (order string, touches []sqlf.FragmentBuilder, err error) {
 	f := sqlf.F("#join('#fragment', ', ')").WithPrefix("ORDER BY")
 	for i, item := range b.orders {
-		if item.order > DescNullsLast {
-			b.pushError(fmt.Errorf("invalid order: %d", item.order))
-			continue
-		}
-		if !b.distinct {
+		// an alias reference is in the select list already
+		if !b.distinct || item.column.ref {
 			f.AppendFragments(sqlf.Ff(
 				"#f1 "+orders[item.order],
 				item.column,
@@ -56,10 +59,14 @@ func (b *QueryBuilder) buildOrders(ctx *sqlf.Context) (string, error) {
 		// pq: for SELECT DISTINCT, ORDER BY expressions must appear in select list
 		alias := fmt.Sprintf("_order_%d", i+1)
 		orderStr := orders[item.order]
-		b.touches.AppendFragments(sqlf.Ff("#f1 AS "+alias, item.column))
+		touches = append(touches, sqlf.Ff("#f1 AS "+alias, item.column))
 		f.AppendFragments(sqlf.F(
 			fmt.Sprintf("%s %s", alias, orderStr),
 		))
 	}
-	return f.BuildFragment(ctx)
+	order, err = f.BuildFragment(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+	return order, touches, nil
 }
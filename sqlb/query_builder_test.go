@@ -2,6 +2,7 @@ package sqlb_test
 
 import (
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/qjebbs/go-sqlf/v2"
@@ -57,3 +58,262 @@ func TestQueryBuilder(t *testing.T) {
 		t.Errorf("want:\n%v\ngot:\n%v", wantArgs, gotArgs)
 	}
 }
+
+type debugRecorder struct {
+	queries []string
+}
+
+func (r *debugRecorder) OnBuildStart(*sqlf.BuildEvent) {}
+
+func (r *debugRecorder) OnBuildEnd(e *sqlf.BuildEvent) {
+	r.queries = append(r.queries, e.Query)
+}
+
+func TestDebugHook(t *testing.T) {
+	// not parallel, it changes the debug hook
+	r := &debugRecorder{}
+	old := sqlb.DebugHook()
+	sqlb.SetDebugHook(r)
+	defer sqlb.SetDebugHook(old)
+
+	foo := sqlb.NewTableAliased("foo", "f")
+	q := sqlb.NewQueryBuilder().Select(foo.Column("id")).From(foo)
+	if _, _, err := q.BuildQuery(syntax.Dollar); err != nil {
+		t.Fatal(err)
+	}
+	q.Debug()
+	if _, _, err := q.BuildQuery(syntax.Dollar); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"SELECT f.id FROM foo AS f"}
+	if !reflect.DeepEqual(r.queries, want) {
+		t.Errorf("got %q, want %q", r.queries, want)
+	}
+}
+
+func TestBuildIdempotent(t *testing.T) {
+	t.Parallel()
+	foo := sqlb.NewTableAliased("foo", "f")
+	q := sqlb.NewQueryBuilder().
+		Distinct().
+		Select(foo.Column("id")).
+		From(foo).
+		OrderBy(foo.Column("name"), sqlb.Desc)
+	want := "SELECT DISTINCT f.id, f.name AS _order_1 FROM foo AS f ORDER BY _order_1 DESC"
+	for i := 0; i < 2; i++ {
+		got, _, err := q.BuildQuery(syntax.Dollar)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("build %d: got:\n%s\nwant:\n%s", i+1, got, want)
+		}
+	}
+	if _, _, err := sqlb.NewQueryBuilder().
+		Select(foo.Column("id")).
+		From(foo).
+		OrderBy(foo.Column("id"), sqlb.Order(100)).
+		BuildQuery(syntax.Dollar); err == nil {
+		t.Error("want error of invalid order, got nil")
+	}
+}
+
+func TestClone(t *testing.T) {
+	t.Parallel()
+	var (
+		foo = sqlb.NewTableAliased("foo", "f")
+		bar = sqlb.NewTableAliased("bar", "b")
+	)
+	base := sqlb.NewQueryBuilder().
+		Select(foo.Column("id")).
+		From(foo).
+		Where2(foo.Column("deleted"), "=", false)
+	a := base.Clone().
+		LeftJoin(bar, sqlf.Ff("#f1=#f2", bar.Column("foo_id"), foo.Column("id"))).
+		Where2(bar.Column("x"), "=", 1).
+		OrderBy(bar.Column("x"), sqlb.Asc)
+	b := base.Clone().Distinct().GroupBy(foo.Column("id")).Limit(10)
+	testCases := []struct {
+		name string
+		q    *sqlb.QueryBuilder
+		want string
+	}{
+		{
+			name: "base",
+			q:    base,
+			want: "SELECT f.id FROM foo AS f WHERE f.deleted=$1",
+		},
+		{
+			name: "a",
+			q:    a,
+			want: "SELECT f.id FROM foo AS f LEFT JOIN bar AS b ON b.foo_id=f.id WHERE f.deleted=$1 AND b.x=$2 ORDER BY b.x ASC",
+		},
+		{
+			name: "b",
+			q:    b,
+			want: "SELECT DISTINCT f.id FROM foo AS f WHERE f.deleted=$1 GROUP BY f.id LIMIT 10",
+		},
+	}
+	for _, tc := range testCases {
+		got, _, err := tc.q.BuildQuery(syntax.Dollar)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if got != tc.want {
+			t.Errorf("%s: got:\n%s\nwant:\n%s", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestJoinElimination(t *testing.T) {
+	t.Parallel()
+	var (
+		foo = sqlb.NewTableAliased("foo", "f")
+		bar = sqlb.NewTableAliased("bar", "b")
+		baz = sqlb.NewTableAliased("baz", "z")
+	)
+	on := func() *sqlf.Fragment {
+		return sqlf.Ff("#f1=#f2", bar.Column("id"), foo.Column("bar_id"))
+	}
+	base := func() *sqlb.QueryBuilder {
+		return sqlb.NewQueryBuilder().Select(foo.Column("id")).From(foo)
+	}
+	const (
+		eliminated = "SELECT f.id FROM foo AS f"
+		leftJoined = "SELECT f.id FROM foo AS f LEFT JOIN bar AS b ON b.id=f.bar_id"
+		innerJoin  = "SELECT f.id FROM foo AS f INNER JOIN bar AS b ON b.id=f.bar_id"
+	)
+	testCases := []struct {
+		name string
+		q    *sqlb.QueryBuilder
+		want string
+	}{
+		{"left join", base().LeftJoin(bar, on()), leftJoined},
+		{"left join optional", base().LeftJoinOptional(bar, on()), leftJoined},
+		{"left join to-one", base().LeftJoin(bar, on(), sqlb.ToOne()), eliminated},
+		{"left join to-many", base().LeftJoin(bar, on(), sqlb.ToMany()), leftJoined},
+		{"left join to-many distinct", base().Distinct().LeftJoin(bar, on(), sqlb.ToMany()), "SELECT DISTINCT f.id FROM foo AS f"},
+		{"inner join to-one", base().InnerJoin(bar, on(), sqlb.ToOne()), innerJoin},
+		{"inner join guaranteed", base().InnerJoin(bar, on(), sqlb.Guaranteed()), innerJoin},
+		{"inner join guaranteed to-one", base().InnerJoin(bar, on(), sqlb.ToOne(), sqlb.Guaranteed()), eliminated},
+		{"inner join guaranteed distinct", base().Distinct().InnerJoin(bar, on(), sqlb.Guaranteed()), "SELECT DISTINCT f.id FROM foo AS f"},
+		{
+			"referenced",
+			base().LeftJoin(bar, on(), sqlb.ToOne()).Where2(bar.Column("x"), "=", 1),
+			"SELECT f.id FROM foo AS f LEFT JOIN bar AS b ON b.id=f.bar_id WHERE b.x=$1",
+		},
+		{
+			"referenced by a join not eliminable",
+			base().LeftJoin(bar, on(), sqlb.ToOne()).
+				InnerJoin(baz, sqlf.Ff("#f1=#f2", baz.Column("bar_id"), bar.Column("id"))),
+			"SELECT f.id FROM foo AS f LEFT JOIN bar AS b ON b.id=f.bar_id INNER JOIN baz AS z ON z.bar_id=b.id",
+		},
+	}
+	for _, tc := range testCases {
+		got, _, err := tc.q.BuildQuery(syntax.Dollar)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if got != tc.want {
+			t.Errorf("%s: got:\n%s\nwant:\n%s", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestSelectList(t *testing.T) {
+	t.Parallel()
+	var (
+		users  = sqlb.NewTableAliased("users", "u")
+		orders = sqlb.NewTableAliased("orders", "o")
+	)
+	total := sqlb.ExprColumn(sqlf.Ff("SUM(#f1)", orders.Column("amount"))).As("total")
+	base := func() *sqlb.QueryBuilder {
+		return sqlb.NewQueryBuilder().
+			Select(users.Column("id")).
+			From(users).
+			LeftJoin(orders, sqlf.Ff("#f1=#f2", orders.Column("user_id"), users.Column("id")))
+	}
+	testCases := []struct {
+		name     string
+		q        *sqlb.QueryBuilder
+		want     string
+		wantArgs []any
+	}{
+		{
+			name: "add select and alias",
+			q: base().
+				AddSelect(users.Column("name").As("user_name"), total).
+				GroupBy(users.Column("id"), users.Column("name").As("user_name").Ref()).
+				OrderBy(total.Ref(), sqlb.Desc),
+			want: "SELECT u.id, u.name AS user_name, SUM(o.amount) AS total " +
+				"FROM users AS u LEFT JOIN orders AS o ON o.user_id=u.id " +
+				"GROUP BY u.id, user_name ORDER BY total DESC",
+		},
+		{
+			name: "distinct order by alias",
+			q: base().
+				Distinct().
+				AddSelect(total).
+				OrderBy(total.Ref(), sqlb.Asc).
+				OrderBy(users.Column("name"), sqlb.Asc),
+			want: "SELECT DISTINCT u.id, SUM(o.amount) AS total, u.name AS _order_2 " +
+				"FROM users AS u LEFT JOIN orders AS o ON o.user_id=u.id " +
+				"ORDER BY total ASC, _order_2 ASC",
+		},
+		{
+			name: "aliased column outside select list",
+			q: base().
+				AddSelect(total).
+				GroupBy(users.Column("id")).
+				Where(sqlf.Ff("#f1 > 0", total)).
+				OrderBy(total, sqlb.Desc),
+			want: "SELECT u.id, SUM(o.amount) AS total " +
+				"FROM users AS u LEFT JOIN orders AS o ON o.user_id=u.id " +
+				"WHERE SUM(o.amount) > 0 GROUP BY u.id ORDER BY SUM(o.amount) DESC",
+		},
+		{
+			name: "remove select",
+			q: base().
+				AddSelect(total, users.Column("name").As("name")).
+				RemoveSelect("total"),
+			want: "SELECT u.id, u.name AS name " +
+				"FROM users AS u LEFT JOIN orders AS o ON o.user_id=u.id",
+		},
+		{
+			name: "select expr",
+			q: base().
+				SelectExpr("COALESCE(u.nickname, $1) AS nickname", "anonymous").
+				Where2(users.Column("type"), "=", "admin"),
+			// the select list is built last
+			want: "SELECT u.id, COALESCE(u.nickname, $2) AS nickname " +
+				"FROM users AS u LEFT JOIN orders AS o ON o.user_id=u.id " +
+				"WHERE u.type=$1",
+			wantArgs: []any{"admin", "anonymous"},
+		},
+	}
+	for _, tc := range testCases {
+		got, args, err := tc.q.BuildQuery(syntax.Dollar)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if got != tc.want {
+			t.Errorf("%s: got:\n%s\nwant:\n%s", tc.name, got, tc.want)
+		}
+		if tc.wantArgs != nil && !reflect.DeepEqual(args, tc.wantArgs) {
+			t.Errorf("%s: got args %v, want %v", tc.name, args, tc.wantArgs)
+		}
+	}
+	// the aliases are not parsed as the syntax of fragments
+	for _, alias := range []string{"n$1", "a b", "1st", "x#f1"} {
+		c := users.Column("id").As(alias)
+		for _, q := range []*sqlb.QueryBuilder{
+			base().AddSelect(c),
+			base().OrderBy(c.Ref(), sqlb.Asc),
+		} {
+			_, _, err := q.BuildQuery(syntax.Dollar)
+			if err == nil || !strings.Contains(err.Error(), "alias") {
+				t.Errorf("alias %q: want alias error, got %v", alias, err)
+			}
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package sqlb
 
 import (
+	"fmt"
+
 	"github.com/qjebbs/go-sqlf/v2"
 	"github.com/qjebbs/go-sqlf/v2/util"
 )
@@ -57,3 +59,51 @@ func (b *QueryBuilder) WhereNotIn(column *Column, list any) *QueryBuilder {
 			WithArgs(util.ArgsFlatted(list)...),
 	)
 }
+
+// WhereExists adds a where EXISTS condition like `EXISTS (SELECT ...)`.
+// The query can be correlated with the tables of b, see Subquery.
+func (b *QueryBuilder) WhereExists(query sqlf.FragmentBuilder) *QueryBuilder {
+	return b.whereSubquery("EXISTS", query, nil)
+}
+
+// WhereNotExists adds a where NOT EXISTS condition like `NOT EXISTS (SELECT ...)`.
+// The query can be correlated with the tables of b, see Subquery.
+func (b *QueryBuilder) WhereNotExists(query sqlf.FragmentBuilder) *QueryBuilder {
+	return b.whereSubquery("NOT EXISTS", query, nil)
+}
+
+// WhereInSubquery adds a where IN condition like `t.id IN (SELECT ...)`.
+// The query can be correlated with the tables of b, see Subquery.
+func (b *QueryBuilder) WhereInSubquery(column *Column, query sqlf.FragmentBuilder) *QueryBuilder {
+	return b.whereColumnSubquery("IN", query, column)
+}
+
+// WhereNotInSubquery adds a where NOT IN condition like `t.id NOT IN (SELECT ...)`.
+// The query can be correlated with the tables of b, see Subquery.
+func (b *QueryBuilder) WhereNotInSubquery(column *Column, query sqlf.FragmentBuilder) *QueryBuilder {
+	return b.whereColumnSubquery("NOT IN", query, column)
+}
+
+// whereColumnSubquery adds the condition 'column op (query)', which
+// requires the column.
+func (b *QueryBuilder) whereColumnSubquery(op string, query sqlf.FragmentBuilder, column *Column) *QueryBuilder {
+	if column == nil {
+		b.pushError(fmt.Errorf("nil column for %s subquery", op))
+		return b
+	}
+	return b.whereSubquery(op, query, column)
+}
+
+// whereSubquery adds the condition 'op (query)', or 'column op (query)'
+// if column is not nil.
+func (b *QueryBuilder) whereSubquery(op string, query sqlf.FragmentBuilder, column *Column) *QueryBuilder {
+	if sqlf.IsNil(query) {
+		// dropping the condition silently widens the result set
+		b.pushError(fmt.Errorf("nil subquery for %s", op))
+		return b
+	}
+	if column == nil {
+		return b.Where(sqlf.Ff(op+" (#f1)", query))
+	}
+	return b.Where(sqlf.Ff("#f1 "+op+" (#f2)", column, query))
+}
@@ -0,0 +1,197 @@
+package sqlb
+
+import (
+	"fmt"
+
+	"github.com/qjebbs/go-sqlf/v2"
+)
+
+// Schema holds the relationships between tables, with which a
+// *QueryBuilder joins the tables referenced by the query automatically.
+// See QueryBuilder.WithSchema.
+//
+// Declare the relationships once, e.g. at startup, a Schema is safe for
+// concurrent use by the builders as long as it's not changed.
+type Schema struct {
+	tables map[Table]TableAliased
+	edges  map[Table][]*relation // by the applied name of the source table
+}
+
+// relation is a directed edge of the relationships, the target is
+// joined with 'target.targetColumn = source.sourceColumn'.
+type relation struct {
+	source       TableAliased
+	sourceColumn string
+	target       TableAliased
+	targetColumn string
+	toOne        bool
+}
+
+// NewSchema returns a new Schema.
+func NewSchema() *Schema {
+	return &Schema{
+		tables: make(map[Table]TableAliased),
+		edges:  make(map[Table][]*relation),
+	}
+}
+
+// Relate declares a foreign key, that fromColumn of the table from
+// references toColumn of the table to, e.g.:
+//
+//	schema.Relate(orders, "user_id", users, "id")
+//
+// The tables are identified by their applied names, i.e. the aliases if
+// any. It can be joined in both directions: from orders to users as a
+// to-one LEFT JOIN, and from users to orders as a to-many LEFT JOIN.
+func (s *Schema) Relate(from TableAliased, fromColumn string, to TableAliased, toColumn string) *Schema {
+	s.tables[from.AppliedName()] = from
+	s.tables[to.AppliedName()] = to
+	s.edges[from.AppliedName()] = append(s.edges[from.AppliedName()], &relation{
+		source:       from,
+		sourceColumn: fromColumn,
+		target:       to,
+		targetColumn: toColumn,
+		toOne:        true,
+	})
+	s.edges[to.AppliedName()] = append(s.edges[to.AppliedName()], &relation{
+		source:       to,
+		sourceColumn: toColumn,
+		target:       from,
+		targetColumn: fromColumn,
+		toOne:        false,
+	})
+	return s
+}
+
+// path finds the shortest join path from any of the present tables to
+// the target, it reports an error if there are more than one.
+func (s *Schema) path(present map[Table]bool, target Table) ([]*relation, error) {
+	if _, ok := s.tables[target]; !ok {
+		return nil, nil
+	}
+	type node struct {
+		via   *relation // the relation reaching the node on a shortest path
+		paths int       // the number of the shortest paths, capped at 2
+	}
+	nodes := make(map[Table]*node)
+	var queue []Table
+	for t := range present {
+		nodes[t] = &node{paths: 1}
+		queue = append(queue, t)
+	}
+	for len(queue) > 0 {
+		var next []Table
+		reached := make(map[Table]bool)
+		for _, t := range queue {
+			for _, r := range s.edges[t] {
+				to := r.target.AppliedName()
+				n, ok := nodes[to]
+				if ok && !reached[to] {
+					// reached in previous levels
+					continue
+				}
+				if !ok {
+					n = &node{via: r}
+					nodes[to] = n
+					reached[to] = true
+					next = append(next, to)
+				}
+				n.paths += nodes[t].paths
+				if n.paths > 2 {
+					n.paths = 2
+				}
+			}
+		}
+		if n, ok := nodes[target]; ok {
+			if n.paths > 1 {
+				return nil, fmt.Errorf("ambiguous join paths to '%s', join it explicitly", target)
+			}
+			var path []*relation
+			for r := n.via; r != nil; r = nodes[r.source.AppliedName()].via {
+				path = append([]*relation{r}, path...)
+			}
+			return path, nil
+		}
+		queue = next
+	}
+	return nil, nil
+}
+
+// WithSchema sets the schema, with which the builder joins the tables
+// referenced by the selects, conditions, orders, etc. but not joined
+// explicitly, including the ones on a multi-hop path.
+//
+//	schema := sqlb.NewSchema().
+//		Relate(orders, "user_id", users, "id").
+//		Relate(users, "company_id", companies, "id")
+//	sqlb.NewQueryBuilder().WithSchema(schema).
+//		Select(orders.Column("id"), companies.Column("name")).
+//		From(orders)
+//	// SELECT o.id, c.name FROM orders AS o
+//	// LEFT JOIN users AS u ON u.id=o.user_id
+//	// LEFT JOIN companies AS c ON c.id=u.company_id
+//
+// The explicit joins take precedence, and the ambiguous paths are
+// reported as errors. A to-many join, e.g. from users to orders, is
+// inferred only for the Distinct() queries, since it duplicates the rows
+// otherwise; join it explicitly, or use WhereExists instead.
+func (b *QueryBuilder) WithSchema(s *Schema) *QueryBuilder {
+	b.schema = s
+	return b
+}
+
+// inferJoins returns a clone of b with the joins inferred by the schema
+// added, so that b is not changed by the building. The tables of the
+// outer queries in ctx are not inferred.
+func (b *QueryBuilder) inferJoins(ctx *sqlf.Context) (*QueryBuilder, error) {
+	var missing []Table
+	outer := scopeOf(ctx)
+	referenced, _ := b.referencedTables()
+	for _, t := range b.tables {
+		if t.Fragment != nil {
+			referenced = append(referenced, extractTables(t.Fragment)...)
+		}
+	}
+	for _, t := range referenced {
+		if _, ok := b.tablesDict[t]; !ok && !outer.has(t) {
+			missing = append(missing, t)
+		}
+	}
+	if len(missing) == 0 {
+		return b, nil
+	}
+	r := b.Clone()
+	r.schema = nil
+	present := make(map[Table]bool, len(r.tablesDict))
+	for t := range r.tablesDict {
+		present[t] = true
+	}
+	for _, t := range missing {
+		if present[t] {
+			continue
+		}
+		path, err := b.schema.path(present, t)
+		if err != nil {
+			return nil, err
+		}
+		for _, rel := range path {
+			options := []JoinOption{ToOne()}
+			if !rel.toOne {
+				if !b.distinct {
+					return nil, fmt.Errorf(
+						"the inferred join of '%s' is to-many, which duplicates the rows: join it explicitly, use WhereExists, or Distinct()",
+						rel.target.AppliedName(),
+					)
+				}
+				options = []JoinOption{ToMany()}
+			}
+			r.LeftJoin(rel.target, sqlf.Ff(
+				"#f1=#f2",
+				rel.target.AppliedName().Column(rel.targetColumn),
+				rel.source.AppliedName().Column(rel.sourceColumn),
+			), options...)
+			present[rel.target.AppliedName()] = true
+		}
+	}
+	return r, nil
+}
@@ -0,0 +1,105 @@
+package sqlb_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/qjebbs/go-sqlf/v2"
+	"github.com/qjebbs/go-sqlf/v2/sqlb"
+	"github.com/qjebbs/go-sqlf/v2/syntax"
+)
+
+func TestSchema(t *testing.T) {
+	t.Parallel()
+	var (
+		orders    = sqlb.NewTableAliased("orders", "o")
+		users     = sqlb.NewTableAliased("users", "u")
+		companies = sqlb.NewTableAliased("companies", "c")
+		addresses = sqlb.NewTableAliased("addresses", "a")
+	)
+	schema := sqlb.NewSchema().
+		Relate(orders, "user_id", users, "id").
+		Relate(users, "company_id", companies, "id").
+		Relate(companies, "address_id", addresses, "id").
+		Relate(users, "address_id", addresses, "id")
+	testCases := []struct {
+		name    string
+		q       *sqlb.QueryBuilder
+		want    string
+		wantErr string
+	}{
+		{
+			name: "multi-hop",
+			q: sqlb.NewQueryBuilder().WithSchema(schema).
+				Select(orders.Column("id"), companies.Column("name")).
+				From(orders),
+			want: "SELECT o.id, c.name FROM orders AS o " +
+				"LEFT JOIN users AS u ON u.id=o.user_id " +
+				"LEFT JOIN companies AS c ON c.id=u.company_id",
+		},
+		{
+			name: "reverse and conditions",
+			q: sqlb.NewQueryBuilder().WithSchema(schema).
+				Distinct().
+				Select(users.Column("id")).
+				From(users).
+				Where2(orders.Column("status"), "=", "paid"),
+			want: "SELECT DISTINCT u.id FROM users AS u " +
+				"LEFT JOIN orders AS o ON o.user_id=u.id " +
+				"WHERE o.status=$1",
+		},
+		{
+			name: "to-many without distinct",
+			q: sqlb.NewQueryBuilder().WithSchema(schema).
+				Select(users.Column("id")).
+				From(users).
+				Where2(orders.Column("status"), "=", "paid"),
+			wantErr: "the inferred join of 'o' is to-many",
+		},
+		{
+			name: "explicit join",
+			q: sqlb.NewQueryBuilder().WithSchema(schema).
+				Select(orders.Column("id")).
+				From(orders).
+				InnerJoin(users, sqlf.Ff("#f1=#f2", users.Column("id"), orders.Column("user_id"))).
+				OrderBy(companies.Column("name"), sqlb.Asc),
+			want: "SELECT o.id FROM orders AS o " +
+				"INNER JOIN users AS u ON u.id=o.user_id " +
+				"LEFT JOIN companies AS c ON c.id=u.company_id " +
+				"ORDER BY c.name ASC",
+		},
+		{
+			name: "ambiguous",
+			q: sqlb.NewQueryBuilder().WithSchema(schema).
+				Select(users.Column("name")).
+				From(orders).
+				LeftJoin(companies, sqlf.F("TRUE")),
+			wantErr: "ambiguous join paths to 'u'",
+		},
+		{
+			name: "unknown table",
+			q: sqlb.NewQueryBuilder().WithSchema(schema).
+				Select(sqlb.Table("x").Column("id")).
+				From(orders),
+			wantErr: "from undefined: 'x'",
+		},
+	}
+	for _, tc := range testCases {
+		// build twice, the inference must not change the builder
+		for i := 0; i < 2; i++ {
+			got, _, err := tc.q.BuildQuery(syntax.Dollar)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Errorf("%s: want error %q, got %v", tc.name, tc.wantErr, err)
+				}
+				continue
+			}
+			if err != nil {
+				t.Fatalf("%s: %v", tc.name, err)
+			}
+			if got != tc.want {
+				t.Errorf("%s: got:\n%s\nwant:\n%s", tc.name, got, tc.want)
+			}
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package sqlb
+
+import (
+	"github.com/qjebbs/go-sqlf/v2"
+)
+
+// Subquery wraps a query as a scalar subquery column '(query)', which
+// can be selected, or compared in conditions, e.g.:
+//
+//	total := sqlb.NewQueryBuilder().
+//		Select(sqlb.ExprColumn(sqlf.Ff("SUM(#f1)", orders.Column("amount")))).
+//		From(orders).
+//		Where(sqlf.Ff("#f1=#f2", orders.Column("user_id"), users.Column("id")))
+//	b.Select(users.Column("id"), sqlb.Subquery(total).As("total")).
+//		From(users)
+//	// SELECT u.id, (SELECT SUM(o.amount) FROM orders AS o WHERE o.user_id=u.id) AS total
+//	// FROM users AS u
+//
+// A *QueryBuilder nested in the fragments of another one is correlated
+// with the outer query: the tables it references but doesn't join, like
+// 'u' above, are resolved in the outer queries, and count toward the
+// dependencies of them, so that the joins are not eliminated.
+func Subquery(query sqlf.FragmentBuilder) *Column {
+	return ExprColumn(sqlf.Ff("(#f1)", query))
+}
+
+// nestedQuery is implemented by *QueryBuilder and the types embedding it.
+type nestedQuery interface {
+	queryBuilder() *QueryBuilder
+}
+
+func (b *QueryBuilder) queryBuilder() *QueryBuilder {
+	return b
+}
+
+// outerTables returns the tables referenced by b but not joined by it,
+// which are resolved in the outer queries if b is nested.
+func (b *QueryBuilder) outerTables() []Table {
+	if b == nil {
+		return nil
+	}
+	builders := b.referenceBuilders()
+	for _, t := range b.tables {
+		if t.Fragment != nil {
+			builders = append(builders, t.Fragment)
+		}
+	}
+	builders = append(builders, b.unions...)
+	var r []Table
+	for _, t := range extractTables(builders...) {
+		if _, ok := b.tablesDict[t]; !ok {
+			r = append(r, t)
+		}
+	}
+	return r
+}
+
+// scopeKey is the context key of the scope.
+type scopeKey struct{}
+
+// scope is the tables of a query being built, which are visible to the
+// queries nested in it.
+type scope struct {
+	tables map[Table]*fromTable
+	parent *scope
+}
+
+// contextWithScope returns a new context with the tables of b in scope.
+func (b *QueryBuilder) contextWithScope(ctx *sqlf.Context) *sqlf.Context {
+	return sqlf.ContextWithValue(ctx, scopeKey{}, &scope{
+		tables: b.tablesDict,
+		parent: scopeOf(ctx),
+	})
+}
+
+// scopeOf returns the scope of the outer queries, nil if not nested.
+func scopeOf(ctx *sqlf.Context) *scope {
+	if ctx == nil {
+		return nil
+	}
+	s, _ := ctx.Value(scopeKey{}).(*scope)
+	return s
+}
+
+// has reports whether the table is in the scope or its parents.
+func (s *scope) has(t Table) bool {
+	for ; s != nil; s = s.parent {
+		if _, ok := s.tables[t]; ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,234 @@
+package sqlb_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/qjebbs/go-sqlf/v2"
+	"github.com/qjebbs/go-sqlf/v2/sqlb"
+	"github.com/qjebbs/go-sqlf/v2/syntax"
+)
+
+func TestSubquery(t *testing.T) {
+	t.Parallel()
+	var (
+		users     = sqlb.NewTableAliased("users", "u")
+		profiles  = sqlb.NewTableAliased("profiles", "p")
+		companies = sqlb.NewTableAliased("companies", "c")
+		orders    = sqlb.NewTableAliased("orders", "o")
+		items     = sqlb.NewTableAliased("items", "i")
+	)
+	outer := func() *sqlb.QueryBuilder {
+		return sqlb.NewQueryBuilder().
+			From(users).
+			LeftJoin(profiles, sqlf.Ff("#f1=#f2", profiles.Column("user_id"), users.Column("id")), sqlb.ToOne()).
+			LeftJoin(companies, sqlf.Ff("#f1=#f2", companies.Column("id"), users.Column("company_id")), sqlb.ToOne())
+	}
+	testCases := []struct {
+		name     string
+		q        *sqlb.QueryBuilder
+		want     string
+		wantArgs []any
+		wantErr  string
+	}{
+		{
+			name: "correlated select keeps the join",
+			q: outer().Select(
+				users.Column("id"),
+				sqlb.Subquery(sqlb.NewQueryBuilder().
+					Select(sqlb.ExprColumn(sqlf.F("COUNT(*)"))).
+					From(orders).
+					Where(sqlf.Ff("#f1=#f2", orders.Column("company_id"), companies.Column("id"))).
+					Where2(orders.Column("status"), "=", "paid"),
+				).As("n"),
+			).Where2(users.Column("active"), "=", true),
+			want: "SELECT u.id, (SELECT COUNT(*) FROM orders AS o WHERE o.company_id=c.id AND o.status=$2) AS n " +
+				"FROM users AS u " +
+				"LEFT JOIN companies AS c ON c.id=u.company_id " +
+				"WHERE u.active=$1",
+			wantArgs: []any{true, "paid"},
+		},
+		{
+			name: "correlated condition",
+			q: outer().Select(users.Column("id")).Where(sqlf.Ff(
+				"#f1 > #f2",
+				profiles.Column("credit"),
+				sqlb.Subquery(sqlb.NewQueryBuilder().
+					Select(sqlb.ExprColumn(sqlf.Ff("SUM(#f1)", orders.Column("amount")))).
+					From(orders).
+					Where(sqlf.Ff("#f1=#f2", orders.Column("user_id"), users.Column("id")))),
+			)),
+			want: "SELECT u.id FROM users AS u " +
+				"LEFT JOIN profiles AS p ON p.user_id=u.id " +
+				"WHERE p.credit > (SELECT SUM(o.amount) FROM orders AS o WHERE o.user_id=u.id)",
+		},
+		{
+			name: "multi-level",
+			q: outer().Select(
+				users.Column("id"),
+				sqlb.Subquery(sqlb.NewQueryBuilder().
+					Select(sqlb.ExprColumn(sqlf.F("COUNT(*)"))).
+					From(orders).
+					Where(sqlf.Ff("EXISTS (#f1)", sqlb.NewQueryBuilder().
+						Select(sqlb.ExprColumn(sqlf.F("1"))).
+						From(items).
+						Where(sqlf.Ff("#f1=#f2 AND #f3=#f4",
+							items.Column("order_id"), orders.Column("id"),
+							items.Column("vendor"), profiles.Column("vendor"),
+						)),
+					)),
+				),
+			),
+			want: "SELECT u.id, (SELECT COUNT(*) FROM orders AS o WHERE EXISTS (" +
+				"SELECT 1 FROM items AS i WHERE i.order_id=o.id AND i.vendor=p.vendor" +
+				")) FROM users AS u " +
+				"LEFT JOIN profiles AS p ON p.user_id=u.id",
+		},
+		{
+			name: "inner tables are not outer dependencies",
+			q: outer().Select(
+				users.Column("id"),
+				sqlb.Subquery(sqlb.NewQueryBuilder().
+					Select(companies.Column("name")).
+					From(companies).
+					Where(sqlf.Ff("#f1=#f2", companies.Column("id"), users.Column("company_id")))),
+			),
+			want: "SELECT u.id, (SELECT c.name FROM companies AS c WHERE c.id=u.company_id) FROM users AS u",
+		},
+		{
+			name: "undefined in subquery",
+			q: outer().Select(
+				sqlb.Subquery(sqlb.NewQueryBuilder().
+					Select(orders.Column("id")).
+					From(orders).
+					Where(sqlf.Ff("#f1=#f2", orders.Column("user_id"), sqlb.Table("x").Column("id")))),
+			),
+			wantErr: "from undefined: 'x'",
+		},
+	}
+	for _, tc := range testCases {
+		got, args, err := tc.q.BuildQuery(syntax.Dollar)
+		if tc.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("%s: want error %q, got %v", tc.name, tc.wantErr, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if got != tc.want {
+			t.Errorf("%s: got:\n%s\nwant:\n%s", tc.name, got, tc.want)
+		}
+		if len(args) != 0 || len(tc.wantArgs) != 0 {
+			if !reflect.DeepEqual(args, tc.wantArgs) {
+				t.Errorf("%s: got args %v, want %v", tc.name, args, tc.wantArgs)
+			}
+		}
+	}
+}
+
+func TestSubquerySchema(t *testing.T) {
+	t.Parallel()
+	var (
+		users  = sqlb.NewTableAliased("users", "u")
+		orders = sqlb.NewTableAliased("orders", "o")
+		items  = sqlb.NewTableAliased("items", "i")
+	)
+	schema := sqlb.NewSchema().
+		Relate(orders, "user_id", users, "id").
+		Relate(items, "order_id", orders, "id")
+	// the outer infers the join of 'o' referenced by the subquery,
+	// while the subquery doesn't infer it again.
+	q := sqlb.NewQueryBuilder().WithSchema(schema).
+		Select(
+			items.Column("id"),
+			sqlb.Subquery(sqlb.NewQueryBuilder().WithSchema(schema).
+				Select(users.Column("name")).
+				From(users).
+				Where(sqlf.Ff("#f1=#f2", users.Column("id"), orders.Column("user_id")))),
+		).
+		From(items)
+	got, _, err := q.BuildQuery(syntax.Dollar)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "SELECT i.id, (SELECT u.name FROM users AS u WHERE u.id=o.user_id) " +
+		"FROM items AS i LEFT JOIN orders AS o ON o.id=i.order_id"
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestWhereSubquery(t *testing.T) {
+	t.Parallel()
+	var (
+		users    = sqlb.NewTableAliased("users", "u")
+		profiles = sqlb.NewTableAliased("profiles", "p")
+		orders   = sqlb.NewTableAliased("orders", "o")
+		bans     = sqlb.NewTableAliased("bans", "b")
+	)
+	ordersOf := func(user *sqlb.Column, status string) *sqlb.QueryBuilder {
+		return sqlb.NewQueryBuilder().
+			Select(sqlb.ExprColumn(sqlf.F("1"))).
+			From(orders).
+			Where(sqlf.Ff("#f1=#f2", orders.Column("user_id"), user)).
+			Where2(orders.Column("status"), "=", status)
+	}
+	q := sqlb.NewQueryBuilder().
+		Select(users.Column("id")).
+		From(users).
+		LeftJoin(profiles, sqlf.Ff("#f1=#f2", profiles.Column("user_id"), users.Column("id")), sqlb.ToOne()).
+		Where2(users.Column("active"), "=", true).
+		WhereExists(ordersOf(profiles.Column("user_id"), "paid")).
+		WhereNotExists(ordersOf(users.Column("id"), "refunded")).
+		WhereInSubquery(users.Column("country"), sqlf.Fa("SELECT code FROM countries WHERE region = $1", "eu")).
+		WhereNotInSubquery(users.Column("id"), sqlb.NewQueryBuilder().
+			Select(bans.Column("user_id")).
+			From(bans).
+			Where2(bans.Column("until"), ">", "now"))
+	got, args, err := q.BuildQuery(syntax.Dollar)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the profiles is kept for the correlation of the EXISTS
+	want := "SELECT u.id FROM users AS u " +
+		"LEFT JOIN profiles AS p ON p.user_id=u.id " +
+		"WHERE u.active=$1 " +
+		"AND EXISTS (SELECT 1 FROM orders AS o WHERE o.user_id=p.user_id AND o.status=$2) " +
+		"AND NOT EXISTS (SELECT 1 FROM orders AS o WHERE o.user_id=u.id AND o.status=$3) " +
+		"AND u.country IN (SELECT code FROM countries WHERE region = $4) " +
+		"AND u.id NOT IN (SELECT b.user_id FROM bans AS b WHERE b.until>$5)"
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+	wantArgs := []any{true, "paid", "refunded", "eu", "now"}
+	if !reflect.DeepEqual(args, wantArgs) {
+		t.Errorf("got args %v, want %v", args, wantArgs)
+	}
+}
+
+func TestWhereSubqueryNil(t *testing.T) {
+	t.Parallel()
+	users := sqlb.NewTableAliased("users", "u")
+	var nilQuery *sqlb.QueryBuilder
+	query := func() *sqlb.QueryBuilder {
+		return sqlb.NewQueryBuilder().Select(users.Column("id")).From(users)
+	}
+	testCases := []struct {
+		q       *sqlb.QueryBuilder
+		wantErr string
+	}{
+		{query().WhereExists(nil), "nil subquery for EXISTS"},
+		{query().WhereNotExists(nilQuery), "nil subquery for NOT EXISTS"},
+		{query().WhereInSubquery(users.Column("id"), nilQuery), "nil subquery for IN"},
+		{query().WhereInSubquery(nil, query()), "nil column for IN subquery"},
+		{query().WhereNotInSubquery(nil, query()), "nil column for NOT IN subquery"},
+	}
+	for _, tc := range testCases {
+		if _, _, err := tc.q.BuildQuery(syntax.Dollar); err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+			t.Errorf("want error %q, got %v", tc.wantErr, err)
+		}
+	}
+}
@@ -1,10 +1,14 @@
 package sqlb
 
 import (
+	"fmt"
+	"unicode"
+
 	"github.com/qjebbs/go-sqlf/v2"
 )
 
 var _ sqlf.FragmentBuilder = (*Column)(nil)
+var _ sqlf.Parent = (*Column)(nil)
 
 // Column is a Column of a table.
 type Column struct {
@@ -16,13 +20,33 @@ type Column struct {
 	// so in this case, we store table here, calcDependency() don't extract
 	// table from 'fragment' if it see a non-empty table here.
 	table Table
+
+	alias string // the alias set by As()
+	ref   bool   // whether it references an alias, see Ref()
 }
 
 // BuildFragment implements FragmentBuilder
 func (c *Column) BuildFragment(ctx *sqlf.Context) (query string, err error) {
+	if c.ref {
+		if err := checkAlias(c.alias); err != nil {
+			return "", err
+		}
+		return c.alias, nil
+	}
 	return c.fragment.BuildFragment(ctx)
 }
 
+// Children implements sqlf.Parent
+func (c *Column) Children() []sqlf.FragmentBuilder {
+	if c == nil || c.ref {
+		return nil
+	}
+	if c.table == "" {
+		return []sqlf.FragmentBuilder{c.fragment}
+	}
+	return []sqlf.FragmentBuilder{c.fragment, c.table}
+}
+
 // ExprColumn wraps a *Fragment of column expression to a *Column.
 //
 // A complex expression column is rather a fragment than a regular column,
@@ -52,3 +76,73 @@ func ExprColumns(fragment ...*sqlf.Fragment) []*Column {
 	}
 	return r
 }
+
+// As returns the column aliased, which is selected as 'expr AS alias'
+// in the select list, and built as the bare expr elsewhere, e.g. in
+// WHERE. It can be removed from the select list by the alias with
+// *QueryBuilder.RemoveSelect, and referenced in ORDER BY and GROUP BY
+// with Ref(), e.g.:
+//
+//	total := sqlb.ExprColumn(sqlf.F("SUM(o.amount)")).As("total")
+//	b.Select(users.Column("id"), total).
+//		GroupBy(users.Column("id")).
+//		OrderBy(total.Ref(), sqlb.Desc)
+//	// SELECT u.id, SUM(o.amount) AS total ... GROUP BY u.id ORDER BY total DESC
+//
+// The alias must be an identifier of letters, digits and underscores,
+// not starting with a digit, or the building fails.
+func (c *Column) As(alias string) *Column {
+	return &Column{
+		fragment: c.fragment,
+		table:    c.table,
+		alias:    alias,
+		ref:      c.ref,
+	}
+}
+
+// Alias returns the alias of the column set by As(), if any.
+func (c *Column) Alias() string {
+	return c.alias
+}
+
+// Ref returns the handle referencing the alias of the column, to use in
+// ORDER BY and GROUP BY. It returns c itself if c is not aliased.
+func (c *Column) Ref() *Column {
+	if c.alias == "" {
+		return c
+	}
+	return &Column{
+		alias: c.alias,
+		ref:   true,
+	}
+}
+
+var _ sqlf.FragmentBuilder = (*selectColumn)(nil)
+
+// selectColumn is an aliased column in the select list.
+type selectColumn struct {
+	*Column
+}
+
+// BuildFragment implements FragmentBuilder
+func (c selectColumn) BuildFragment(ctx *sqlf.Context) (query string, err error) {
+	if err := checkAlias(c.alias); err != nil {
+		return "", err
+	}
+	query, err = c.Column.BuildFragment(ctx)
+	if err != nil || query == "" {
+		return query, err
+	}
+	return query + " AS " + c.alias, nil
+}
+
+// checkAlias checks whether the alias is a valid identifier.
+func checkAlias(alias string) error {
+	for i, r := range alias {
+		if r == '_' || unicode.IsLetter(r) || i > 0 && unicode.IsDigit(r) {
+			continue
+		}
+		return fmt.Errorf("invalid column alias %q", alias)
+	}
+	return nil
+}
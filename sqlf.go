@@ -31,14 +31,50 @@
 //
 // # Preprocessing Functions
 //
-//   - f, fragment: fragments at index, e.g. #f1
+//   - f, fragment: fragments at index or name, e.g. #f1, #f('user_filter')
 //   - join: Join the template with separator, e.g. #join('#f', ', '), #join('#arg', ',', 3), #join('#arg', ',', 3, 6)
-//   - arg: arguments at index, usually used in #join().
+//   - arg: arguments at index or name, usually used in #join(), e.g. #arg('user_id')
+//
+// # Named References
+//
+// Args and fragments can be named with Fragment.AppendNamedArg and
+// Fragment.AppendNamedFragment, and referenced by name instead of index,
+// which keeps the Raw readable when a fragment has many children:
+//
+//	sqlf.F("SELECT * FROM users WHERE #f('user_filter') AND id > #arg(min_id)").
+//		AppendNamedFragment("user_filter", filter).
+//		AppendNamedArg("min_id", 100)
+//
+// A bare name like #f(user_filter) is equivalent to #f('user_filter').
+// Named items are still counted by position, so #join iterates them in
+// the declared order, and the unused check reports them by index.
+//
+// # Conditional Sections
+//
+// A section between #if(cond) and #end is built only if the cond is true,
+// otherwise, the optional section between #else and #end is built instead.
+//
+//	SELECT * FROM foo#if(f1) WHERE #f1#end
+//
+// The cond is a function call without '#', e.g. #if(f1), #if(arg, 2), where
+//   - #if(f1): true if the fragment at index 1 builds not empty.
+//   - #if(arg1): true if the arg at index 1 is not NULL, see IsNil.
+//   - #if(my_func): calls a custom function, true if it returns true or a
+//     not-empty string. See FuncMap for functions returning bool.
+//
+// Conditions are evaluated without committing any args, and the args and
+// fragments referenced in the section not built are still counted as used.
 //
 // Note:
 //   - #f1 is equivalent to #f(1), which is a special syntax to call preprocessing functions when an integer (usually an index) is the only argument.
 //   - Expressions in the #join template are functions, not function calls.
-//   - You can register custom functions to the build context, see ContextWithFuncs.
+//   - You can register custom functions to the build context, see ContextWithFuncs, or
+//     NewFuncEnv and ContextWithFuncEnv to validate them once for many concurrent builds.
+//   - Comments, quoted and dollar-quoted strings are kept as is, use \?, \$ and \# for the
+//     literal ?, $ and # elsewhere, e.g. the JSONB operator \?|. For MySQL strings escaped
+//     by backslashes, see WithParseOptions and syntax.WithBackslashEscapes.
+//   - Custom functions can take nested calls and function values as args,
+//     e.g. #my_func(#f1, #arg), see FuncMap, FragmentBuilder and FuncValue.
 package sqlf
 
 import "github.com/qjebbs/go-sqlf/v2/syntax"
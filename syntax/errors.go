@@ -0,0 +1,14 @@
+package syntax
+
+import "fmt"
+
+// Error is the syntax error reported by Parse.
+type Error struct {
+	Pos    Pos    // position of the error
+	Offset int    // byte offset of the error in the input
+	Msg    string // error message
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: syntax error: %s", e.Pos, e.Msg)
+}
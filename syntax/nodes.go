@@ -8,15 +8,29 @@ type Clause struct {
 // Expr is the declaration.
 type Expr interface {
 	Node
+	// String returns the canonical form of the expression,
+	// which is parsed back to an equivalent expression.
+	// See Clause.String.
+	String() string
 	aExpr()
 }
 
 // Node is the node.
 type Node interface {
 	Pos() Pos
+	// Span returns the range of the node in the input,
+	// which is zero for the nodes not created by Parse.
+	Span() Span
 	aNode()
 }
 
+// Span is the range of a node in the input, in byte offsets,
+// the input[Start:End] is the source of the node.
+type Span struct {
+	Start int
+	End   int
+}
+
 type expr struct {
 	node
 }
@@ -24,11 +38,13 @@ type expr struct {
 func (*expr) aExpr() {}
 
 type node struct {
-	pos Pos
+	pos  Pos
+	span Span
 }
 
-func (n *node) Pos() Pos { return n.pos }
-func (*node) aNode()     {}
+func (n *node) Pos() Pos   { return n.pos }
+func (n *node) Span() Span { return n.span }
+func (*node) aNode()       {}
 
 // BindVarExpr is the reference declaration.
 type BindVarExpr struct {
@@ -50,7 +66,10 @@ const (
 // FuncCallExpr is the function calling declaration.
 type FuncCallExpr struct {
 	Name string
-	Args []any // number (float64, int, uint...), string, bool, nil
+	Args []any // number (float64, int, uint...), string, bool, nil, or *FuncCallExpr, *FuncExpr for nested ones
+	// Short reports whether it's written in the short form like #f1,
+	// or f1 as the condition of #if, which takes a single index.
+	Short bool
 	expr
 }
 
@@ -63,5 +82,18 @@ type FuncExpr struct {
 // PlainExpr is the plain text declaration.
 type PlainExpr struct {
 	Text string
+	// Escaped reports whether the first character of Text
+	// is escaped by a backslash in the source, e.g.: \?
+	Escaped bool
+	expr
+}
+
+// IfExpr is the conditional section declaration:
+//
+//	#if(cond) ... #else ... #end
+type IfExpr struct {
+	Cond *FuncCallExpr // the condition, a function call
+	Then []Expr        // expressions built if the condition is true
+	Else []Expr        // expressions built if the condition is false, not nil if #else is written
 	expr
 }
@@ -4,13 +4,40 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"unicode"
 )
 
+// ParseOption is the option of Parse.
+type ParseOption func(*parseOptions)
+
+type parseOptions struct {
+	backslashEscapes bool
+}
+
+// WithBackslashEscapes makes backslashes escape characters in all quoted
+// strings, which is the default behaviour of MySQL. Without it, only the
+// PostgreSQL escape strings like E'a\'b' are escaped by backslashes.
+func WithBackslashEscapes() ParseOption {
+	return func(o *parseOptions) {
+		o.backslashEscapes = true
+	}
+}
+
 // Parse parses the input and returns the list of expressions.
-func Parse(input string) (*Clause, error) {
+//
+// The comments (-- and /* */), quoted strings and dollar-quoted strings
+// ($$...$$, $tag$...$tag$) are kept as plain text, where bindvars and
+// functions are not recognized. Outside them, \?, \$ and \# are escapes
+// for the literal ?, $ and #.
+func Parse(input string, options ...ParseOption) (*Clause, error) {
+	opts := &parseOptions{}
+	for _, opt := range options {
+		opt(opts)
+	}
 	p := &parser{
 		scanner: newScanner(input),
 	}
+	p.backslashEscapes = opts.backslashEscapes
 	if err := p.Parse(); err != nil {
 		return nil, err
 	}
@@ -24,13 +51,34 @@ type parser struct {
 	bindVarStyle BindVarStyle
 	// buf []token
 
-	c *Clause
+	c      *Clause
+	blocks []*ifBlock // the open #if blocks
+}
+
+type ifBlock struct {
+	*IfExpr
+	offset int // offset of the #if
+	inElse bool
+}
+
+// append appends the expressions to the current block.
+func (p *parser) append(exprs ...Expr) {
+	if len(p.blocks) == 0 {
+		p.c.ExprList = append(p.c.ExprList, exprs...)
+		return
+	}
+	b := p.blocks[len(p.blocks)-1]
+	if b.inElse {
+		b.Else = append(b.Else, exprs...)
+		return
+	}
+	b.Then = append(b.Then, exprs...)
 }
 
 func (p *parser) want(t TokenType) error {
 	if !p.got(t) {
 		return p.syntaxError(
-			fmt.Sprintf("syntax error: unexpected %s, want %s", p.token.typ, t),
+			fmt.Sprintf("unexpected %s, want %s", p.token.typ, t),
 		)
 	}
 	return nil
@@ -45,7 +93,11 @@ func (p *parser) got(tok TokenType) bool {
 }
 
 func (p *parser) syntaxError(msg string) error {
-	return fmt.Errorf("%d: syntax error: %s", p.token.start, msg)
+	return &Error{
+		Pos:    p.token.pos,
+		Offset: p.token.start,
+		Msg:    msg,
+	}
 }
 
 func (p *parser) Parse() error {
@@ -59,46 +111,53 @@ func (p *parser) Parse() error {
 			if err != nil {
 				return err
 			}
-			p.c.ExprList = append(p.c.ExprList, d)
+			p.append(d)
 		case _Hash:
 			err := p.funcExpr()
 			if err != nil {
 				return err
 			}
 		case _Plain:
-			p.c.ExprList = append(p.c.ExprList, &PlainExpr{
-				Text: p.token.lit,
-				expr: expr{node{p.token.pos}},
+			start := p.token.start
+			if p.token.escaped {
+				start-- // the backslash
+			}
+			p.append(&PlainExpr{
+				Text:    p.token.lit,
+				Escaped: p.token.escaped,
+				expr:    exprAt(p.token.pos, start, p.token.end),
 			})
 		default:
 			return p.syntaxError("unexpected token " + string(p.token.typ))
 		}
 	}
+	if len(p.blocks) > 0 {
+		b := p.blocks[len(p.blocks)-1]
+		return &Error{
+			Pos:    b.pos,
+			Offset: b.offset,
+			Msg:    "#if without #end",
+		}
+	}
 	return nil
 }
 
 func (p *parser) bindVarExpr() (Expr, error) {
 	pos := p.token.pos
+	start := p.token.start
 	var t BindVarStyle
 	switch p.token.lit {
 	case "$":
 		t = Dollar
-		p.bindVarIndex++
-		if p.bindVarStyle == 0 {
-			p.bindVarStyle = t
-		}
-		if p.bindVarStyle != t {
-			return nil, p.syntaxError("mixed bindvar styles")
-		}
 	case "?":
 		t = Question
-		p.bindVarIndex++
-		if p.bindVarStyle == 0 {
-			p.bindVarStyle = t
-		}
-		if p.bindVarStyle != t {
-			return nil, p.syntaxError("mixed bindvar styles")
-		}
+	}
+	p.bindVarIndex++
+	if p.bindVarIndex == 1 {
+		p.bindVarStyle = t
+	}
+	if p.bindVarStyle != t {
+		return nil, p.syntaxError("mixed bindvar styles")
 	}
 	index := p.bindVarIndex
 	if t != Question {
@@ -117,92 +176,243 @@ func (p *parser) bindVarExpr() (Expr, error) {
 	return &BindVarExpr{
 		Type:  t,
 		Index: index,
-		expr:  expr{node{pos}},
+		expr:  exprAt(pos, start, p.token.end),
 	}, nil
 }
 
 func (p *parser) funcExpr() error {
 	pos := p.token.pos
+	offset := p.token.start
 	if err := p.want(_Name); err != nil {
 		return err
 	}
 	nameToken := p.token
 	p.NextToken()
+	switch nameToken.lit {
+	case "if":
+		return p.ifExpr(pos, offset)
+	case "else", "end":
+		return p.ifKeyword(nameToken)
+	}
+	e, err := p.funcNode(pos, offset, nameToken)
+	if err != nil {
+		return err
+	}
+	p.append(e)
+	return nil
+}
+
+// funcNode parses the function call or value, after the name.
+func (p *parser) funcNode(pos Pos, start int, nameToken *token) (Expr, error) {
+	name := nameToken.lit
 	switch p.token.typ {
 	case _Lparen:
-		args := make([]any, 0)
-		for {
-			if !p.got(_Literal) {
-				if p.token.typ != _Rparen {
-					return p.syntaxError("unexpected token " + string(p.token.typ) + ", want args")
-				}
-				break
-			}
-			if p.token.bad {
-				return p.syntaxError("bad argument: " + p.token.lit)
-			}
-			switch p.token.kind {
-			case _NilLit:
-				args = append(args, nil)
-			case _BoolLit:
-				args = append(args, p.token.lit == "true")
-			case _NumberLit:
-				val, err := strconv.ParseFloat(p.token.lit, 64)
-				if err != nil {
-					return p.syntaxError(err.Error())
-				}
-				args = append(args, val)
-			case _StringLit:
-				arg := strings.ReplaceAll(p.token.lit[1:len(p.token.lit)-1], "''", "'")
-				args = append(args, arg)
-			default:
-				return p.syntaxError("unexpected token " + string(p.token.typ))
+		args, err := p.funcArgs()
+		if err != nil {
+			return nil, err
+		}
+		return &FuncCallExpr{
+			Name: name,
+			Args: args,
+			expr: exprAt(pos, start, p.token.end),
+		}, nil
+	case _Literal:
+		if p.token.kind != _NumberLit {
+			return nil, p.syntaxError("unexpected '" + p.token.lit + "', want index")
+		}
+		val, err := strconv.ParseFloat(p.token.lit, 64)
+		if err != nil {
+			return nil, p.syntaxError(err.Error())
+		}
+		return &FuncCallExpr{
+			Name:  name,
+			Args:  []any{val},
+			Short: true,
+			expr:  exprAt(pos, start, p.token.end),
+		}, nil
+	default:
+		p.backup()
+		return &FuncExpr{
+			Name: name,
+			expr: exprAt(pos, start, nameToken.end),
+		}, nil
+	}
+}
+
+// nestedFunc parses the function call or value as an arg, after the '#'.
+func (p *parser) nestedFunc() (Expr, error) {
+	pos := p.token.pos
+	start := p.token.start
+	if err := p.want(_Name); err != nil {
+		return nil, err
+	}
+	nameToken := p.token
+	switch nameToken.lit {
+	case "if", "else", "end":
+		return nil, p.syntaxError("unexpected #" + nameToken.lit + " in function args")
+	}
+	p.NextToken()
+	return p.funcNode(pos, start, nameToken)
+}
+
+// funcArgs parses the args of a function call, after the '('.
+// A bare name arg is parsed as a string, and a nested function call
+// or value as *FuncCallExpr or *FuncExpr.
+func (p *parser) funcArgs() ([]any, error) {
+	args := make([]any, 0)
+	for {
+		p.NextToken()
+		if p.token.typ == _Hash {
+			e, err := p.nestedFunc()
+			if err != nil {
+				return nil, err
 			}
+			args = append(args, e)
 			if !p.got(_Comma) {
 				break
 			}
+			continue
 		}
-		if p.token.typ != _Rparen {
-			return p.syntaxError("unexpected token " + string(p.token.typ) + ", want )")
+		if p.token.typ != _Literal && p.token.typ != _Name {
+			if p.token.typ != _Rparen {
+				return nil, p.syntaxError("unexpected token " + string(p.token.typ) + ", want args")
+			}
+			break
 		}
-		p.c.ExprList = append(
-			p.c.ExprList,
-			&FuncCallExpr{
-				Name: nameToken.lit,
-				Args: args,
-				expr: expr{node{pos}},
-			},
-		)
-	case _Literal:
-		if p.token.kind != _NumberLit {
-			return p.syntaxError("unexpected '" + p.token.lit + "', want index")
+		arg, err := p.literal()
+		if err != nil {
+			return nil, err
 		}
-		val, err := strconv.ParseFloat(p.token.lit, 64)
+		args = append(args, arg)
+		if !p.got(_Comma) {
+			break
+		}
+	}
+	if p.token.typ != _Rparen {
+		return nil, p.syntaxError("unexpected token " + string(p.token.typ) + ", want )")
+	}
+	return args, nil
+}
+
+// literal returns the value of current literal token.
+func (p *parser) literal() (any, error) {
+	if p.token.bad {
+		return nil, p.syntaxError("bad argument: " + p.token.lit)
+	}
+	if p.token.typ == _Name {
+		// bare name, e.g. #f(name), is equivalent to #f('name')
+		return strings.TrimSpace(p.token.lit), nil
+	}
+	switch p.token.kind {
+	case _NilLit:
+		return nil, nil
+	case _BoolLit:
+		return strings.TrimSpace(p.token.lit) == "true", nil
+	case _NumberLit:
+		val, err := strconv.ParseFloat(strings.TrimSpace(p.token.lit), 64)
 		if err != nil {
-			return p.syntaxError(err.Error())
+			return nil, p.syntaxError(err.Error())
 		}
-		p.c.ExprList = append(
-			p.c.ExprList,
-			&FuncCallExpr{
-				Name: nameToken.lit,
-				Args: []any{val},
-				expr: expr{node{pos}},
-			},
-		)
+		return val, nil
+	case _StringLit:
+		return strings.ReplaceAll(p.token.lit[1:len(p.token.lit)-1], "''", "'"), nil
 	default:
-		// EOF or _Plain
-		p.c.ExprList = append(
-			p.c.ExprList,
-			&FuncExpr{
-				Name: nameToken.lit,
-				expr: expr{node{pos}},
-			},
-			&PlainExpr{
-				Text: p.token.lit,
-				expr: expr{node{p.token.pos}},
-			},
-		)
+		return nil, p.syntaxError("unexpected token " + string(p.token.typ))
 	}
+}
 
+// ifExpr parses #if(cond, args...), after the name 'if'.
+//
+// The cond is a function name, optionally followed by an index like #f1,
+// which is equivalent to #if(f, 1).
+func (p *parser) ifExpr(pos Pos, offset int) error {
+	if p.token.typ != _Lparen {
+		return p.syntaxError("unexpected token " + string(p.token.typ) + ", want (")
+	}
+	if !p.got(_Name) {
+		return p.syntaxError("unexpected token " + string(p.token.typ) + ", want condition")
+	}
+	condPos := p.token.pos
+	condStart := p.token.start
+	name, index, ok := splitCondName(strings.TrimSpace(p.token.lit))
+	if !ok {
+		return p.syntaxError("bad condition: " + p.token.lit)
+	}
+	cond := &FuncCallExpr{
+		Name: name,
+		Args: make([]any, 0),
+	}
+	if index > 0 {
+		cond.Args = append(cond.Args, float64(index))
+		cond.Short = true
+	}
+	if p.got(_Comma) {
+		args, err := p.funcArgs()
+		if err != nil {
+			return err
+		}
+		cond.Args = append(cond.Args, args...)
+	}
+	if p.token.typ != _Rparen {
+		return p.syntaxError("unexpected token " + string(p.token.typ) + ", want )")
+	}
+	condEnd := p.token.start
+	for condEnd > condStart && unicode.IsSpace(rune(p.input[condEnd-1])) {
+		condEnd--
+	}
+	cond.expr = exprAt(condPos, condStart, condEnd)
+	e := &IfExpr{
+		Cond: cond,
+		expr: exprAt(pos, offset, p.token.end),
+	}
+	p.append(e)
+	p.blocks = append(p.blocks, &ifBlock{IfExpr: e, offset: offset})
 	return nil
 }
+
+// ifKeyword handles #else and #end.
+func (p *parser) ifKeyword(nameToken *token) error {
+	keyword := nameToken.lit
+	p.backup()
+	if len(p.blocks) == 0 {
+		return p.syntaxError("#" + keyword + " without #if")
+	}
+	b := p.blocks[len(p.blocks)-1]
+	if keyword == "end" {
+		b.span.End = nameToken.end
+		p.blocks = p.blocks[:len(p.blocks)-1]
+		return nil
+	}
+	if b.inElse {
+		return p.syntaxError("duplicate #else")
+	}
+	b.inElse = true
+	if b.Else == nil {
+		b.Else = []Expr{}
+	}
+	return nil
+}
+
+// splitCondName splits cond name like 'f1' into 'f' and 1.
+func splitCondName(s string) (name string, index int, ok bool) {
+	i := 0
+	for i < len(s) && (s[i] == '_' || 'a' <= s[i]|0x20 && s[i]|0x20 <= 'z') {
+		i++
+	}
+	if i == 0 {
+		return "", 0, false
+	}
+	name = s[:i]
+	if i == len(s) {
+		return name, 0, true
+	}
+	index, err := strconv.Atoi(s[i:])
+	if err != nil || index <= 0 {
+		return "", 0, false
+	}
+	return name, index, true
+}
+
+func exprAt(pos Pos, start, end int) expr {
+	return expr{node{pos: pos, span: Span{Start: start, End: end}}}
+}
@@ -1,13 +1,15 @@
 package syntax
 
 import (
+	"fmt"
 	"reflect"
+	"strings"
 	"testing"
 )
 
 func TestParser(t *testing.T) {
 	newExpr := func(line, col uint) expr {
-		return expr{node{Pos{line, col}}}
+		return expr{node{pos: Pos{line, col}}}
 	}
 	testCases := []struct {
 		raw     string
@@ -26,6 +28,39 @@ func TestParser(t *testing.T) {
 			raw:     "$1,?",
 			wantErr: true,
 		},
+		{
+			raw: "#f(user_filter) #f( 'a''b' )",
+			want: []Expr{
+				&FuncCallExpr{Name: "f", Args: []any{"user_filter"}, expr: newExpr(1, 1)},
+				&PlainExpr{Text: " ", expr: newExpr(1, 16)},
+				&FuncCallExpr{Name: "f", Args: []any{"a'b"}, expr: newExpr(1, 17)},
+			},
+		},
+		{
+			raw: "#a(#f1, #arg, #b(#f(2), 'x'))",
+			want: []Expr{
+				&FuncCallExpr{Name: "a", Args: []any{
+					&FuncCallExpr{Name: "f", Args: []any{float64(1)}, Short: true, expr: newExpr(1, 4)},
+					&FuncExpr{Name: "arg", expr: newExpr(1, 9)},
+					&FuncCallExpr{Name: "b", Args: []any{
+						&FuncCallExpr{Name: "f", Args: []any{float64(2)}, expr: newExpr(1, 18)},
+						"x",
+					}, expr: newExpr(1, 15)},
+				}, expr: newExpr(1, 1)},
+			},
+		},
+		{
+			raw:     "#a(#if(f1))",
+			wantErr: true,
+		},
+		{
+			raw:     "#a(#b(1)",
+			wantErr: true,
+		},
+		{
+			raw:     "#f(user filter)",
+			wantErr: true,
+		},
 		{
 			raw: "?,?,?",
 			want: []Expr{
@@ -56,19 +91,78 @@ func TestParser(t *testing.T) {
 		{
 			raw: "#c1#t1#fragment1",
 			want: []Expr{
-				&FuncCallExpr{Name: "c", Args: []any{float64(1)}, expr: newExpr(1, 1)},
-				&FuncCallExpr{Name: "t", Args: []any{float64(1)}, expr: newExpr(1, 4)},
-				&FuncCallExpr{Name: "fragment", Args: []any{float64(1)}, expr: newExpr(1, 7)},
+				&FuncCallExpr{Name: "c", Args: []any{float64(1)}, Short: true, expr: newExpr(1, 1)},
+				&FuncCallExpr{Name: "t", Args: []any{float64(1)}, Short: true, expr: newExpr(1, 4)},
+				&FuncCallExpr{Name: "fragment", Args: []any{float64(1)}, Short: true, expr: newExpr(1, 7)},
+			},
+		},
+		{
+			raw: "#arg#f1",
+			want: []Expr{
+				&FuncExpr{Name: "arg", expr: newExpr(1, 1)},
+				&FuncCallExpr{Name: "f", Args: []any{float64(1)}, Short: true, expr: newExpr(1, 5)},
+			},
+		},
+		{
+			raw: "a#if(f1)b#else c#end d",
+			want: []Expr{
+				&PlainExpr{Text: "a", expr: newExpr(1, 1)},
+				&IfExpr{
+					Cond: &FuncCallExpr{Name: "f", Args: []any{float64(1)}, Short: true, expr: newExpr(1, 6)},
+					Then: []Expr{&PlainExpr{Text: "b", expr: newExpr(1, 9)}},
+					Else: []Expr{&PlainExpr{Text: " c", expr: newExpr(1, 15)}},
+					expr: newExpr(1, 2),
+				},
+				&PlainExpr{Text: " d", expr: newExpr(1, 21)},
 			},
 		},
+		{
+			raw: "#if(debug)#if(has, 'x', 1)$1#end#end",
+			want: []Expr{
+				&IfExpr{
+					Cond: &FuncCallExpr{Name: "debug", Args: []any{}, expr: newExpr(1, 5)},
+					Then: []Expr{
+						&IfExpr{
+							Cond: &FuncCallExpr{Name: "has", Args: []any{"x", float64(1)}, expr: newExpr(1, 15)},
+							Then: []Expr{&BindVarExpr{Type: Dollar, Index: 1, expr: newExpr(1, 27)}},
+							expr: newExpr(1, 11),
+						},
+					},
+					expr: newExpr(1, 1),
+				},
+			},
+		},
+		{
+			raw:     "#if(f1) a",
+			wantErr: true,
+		},
+		{
+			raw:     "a #end",
+			wantErr: true,
+		},
+		{
+			raw:     "#if(f1) a #else b #else c #end",
+			wantErr: true,
+		},
+		{
+			raw:     "#if('a') #end",
+			wantErr: true,
+		},
 	}
 	for _, tc := range testCases {
 		t.Run(tc.raw, func(t *testing.T) {
 			got, err := Parse(tc.raw)
-			if !tc.wantErr && err != nil {
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("want error, got nil")
+				}
+				return
+			}
+			if err != nil {
 				t.Fatal(err)
 			}
-			if !tc.wantErr && !reflect.DeepEqual(got.ExprList, tc.want) {
+			clearSpans(got.ExprList)
+			if !reflect.DeepEqual(got.ExprList, tc.want) {
 				for _, tk := range got.ExprList {
 					t.Logf("%#v", tk)
 				}
@@ -77,3 +171,110 @@ func TestParser(t *testing.T) {
 		})
 	}
 }
+
+// clearSpans clears the spans of the expressions, which are tested in TestSpans.
+func clearSpans(exprs []Expr) {
+	for _, e := range exprs {
+		switch e := e.(type) {
+		case *PlainExpr:
+			e.span = Span{}
+		case *BindVarExpr:
+			e.span = Span{}
+		case *FuncExpr:
+			e.span = Span{}
+		case *FuncCallExpr:
+			e.span = Span{}
+			for _, arg := range e.Args {
+				if arg, ok := arg.(Expr); ok {
+					clearSpans([]Expr{arg})
+				}
+			}
+		case *IfExpr:
+			e.span = Span{}
+			clearSpans([]Expr{e.Cond})
+			clearSpans(e.Then)
+			clearSpans(e.Else)
+		}
+	}
+}
+
+func TestParserSQL(t *testing.T) {
+	// render marks the bindvars and function calls, e.g.: <$1>, <#f>
+	render := func(c *Clause) string {
+		b := new(strings.Builder)
+		for _, e := range c.ExprList {
+			switch e := e.(type) {
+			case *PlainExpr:
+				b.WriteString(e.Text)
+			case *BindVarExpr:
+				if e.Type == Question {
+					b.WriteString("<?>")
+				} else {
+					fmt.Fprintf(b, "<$%d>", e.Index)
+				}
+			case *FuncCallExpr:
+				fmt.Fprintf(b, "<#%s>", e.Name)
+			}
+		}
+		return b.String()
+	}
+	testCases := []struct {
+		raw     string
+		options []ParseOption
+		want    string
+	}{
+		{
+			raw:  "SELECT ? -- what? #f1 $1\nFROM foo WHERE id = ?",
+			want: "SELECT <?> -- what? #f1 $1\nFROM foo WHERE id = <?>",
+		},
+		{
+			raw:  "SELECT $1 /* $2 /* nested ? */ #f1 */ #f1",
+			want: "SELECT <$1> /* $2 /* nested ? */ #f1 */ <#f>",
+		},
+		{
+			raw:  "SELECT $1 -- comment at the end ?",
+			want: "SELECT <$1> -- comment at the end ?",
+		},
+		{
+			raw:  "DO $$ BEGIN PERFORM $1, #f1; END $$; SELECT $1",
+			want: "DO $$ BEGIN PERFORM $1, #f1; END $$; SELECT <$1>",
+		},
+		{
+			raw:  "DO $body$ SELECT $1 $$ ? $body$, $1",
+			want: "DO $body$ SELECT $1 $$ ? $body$, <$1>",
+		},
+		{
+			raw:  "data \\?| array['a'] AND data \\?& $1 AND data #>> '{a}' = \\$1 AND \\#f1",
+			want: "data ?| array['a'] AND data ?& <$1> AND data #>> '{a}' = $1 AND #f1",
+		},
+		{
+			raw:  "SELECT E'it\\'s $1' = $1",
+			want: "SELECT E'it\\'s $1' = <$1>",
+		},
+		{
+			raw:  "SELECT 'a\\' ?', ?",
+			want: "SELECT 'a\\' <?>', ?",
+		},
+		{
+			raw:     "SELECT 'a\\' ?', ?",
+			options: []ParseOption{WithBackslashEscapes()},
+			want:    "SELECT 'a\\' ?', <?>",
+		},
+		{
+			raw:     "SELECT \"a\\\"?\" = ?",
+			options: []ParseOption{WithBackslashEscapes()},
+			want:    "SELECT \"a\\\"?\" = <?>",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.raw, func(t *testing.T) {
+			c, err := Parse(tc.raw, tc.options...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := render(c); got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
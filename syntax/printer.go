@@ -0,0 +1,159 @@
+package syntax
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// String returns the canonical form of the clause, rather than the
+// original input: the function args are quoted and spaced uniformly,
+// e.g. #f( 1 ,name ) is printed as #f(1, 'name'). The canonical form is
+// parsed back to an equivalent clause, and printed as is. Use the spans
+// of the expressions to get the original input.
+func (c *Clause) String() string {
+	return exprsString(c.ExprList)
+}
+
+// Format implements fmt.Formatter, the verb %+v prints the tree
+// of the expressions with their positions, which is useful to debug.
+func (c *Clause) Format(s fmt.State, verb rune) {
+	if verb != 'v' || !s.Flag('+') {
+		io.WriteString(s, c.String())
+		return
+	}
+	printTree(s, c.ExprList, "")
+}
+
+func printTree(w io.Writer, exprs []Expr, indent string) {
+	for _, e := range exprs {
+		span := e.Span()
+		fmt.Fprintf(w, "%s%T %s [%d:%d] %q\n", indent, e, e.Pos(), span.Start, span.End, e.String())
+		switch e := e.(type) {
+		case *FuncCallExpr:
+			var nested []Expr
+			for _, arg := range e.Args {
+				if arg, ok := arg.(Expr); ok {
+					nested = append(nested, arg)
+				}
+			}
+			printTree(w, nested, indent+"\t")
+		case *IfExpr:
+			printTree(w, []Expr{e.Cond}, indent+"\t")
+			printTree(w, e.Then, indent+"\t")
+			if e.Else != nil {
+				fmt.Fprintf(w, "%s\t#else\n", indent)
+				printTree(w, e.Else, indent+"\t")
+			}
+		}
+	}
+}
+
+func (e *PlainExpr) String() string {
+	if e.Escaped {
+		return `\` + e.Text
+	}
+	return e.Text
+}
+
+func (e *BindVarExpr) String() string {
+	if e.Type == Question {
+		return "?"
+	}
+	return "$" + strconv.Itoa(e.Index)
+}
+
+func (e *FuncExpr) String() string {
+	return "#" + e.Name
+}
+
+func (e *FuncCallExpr) String() string {
+	if index, ok := e.shortIndex(); ok && len(e.Args) == 1 {
+		return "#" + e.Name + index
+	}
+	return "#" + e.Name + "(" + argsString(e.Args) + ")"
+}
+
+// condString returns the source of the call as the condition of #if.
+func (e *FuncCallExpr) condString() string {
+	args := e.Args
+	name := e.Name
+	if index, ok := e.shortIndex(); ok {
+		name += index
+		args = args[1:]
+	}
+	if len(args) == 0 {
+		return name
+	}
+	return name + ", " + argsString(args)
+}
+
+// shortIndex returns the index written in the short form.
+func (e *FuncCallExpr) shortIndex() (string, bool) {
+	if !e.Short || len(e.Args) == 0 {
+		return "", false
+	}
+	s := argString(e.Args[0])
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return "", false
+		}
+	}
+	return s, s != "" && s[0] != '0'
+}
+
+func (e *IfExpr) String() string {
+	b := new(strings.Builder)
+	b.WriteString("#if(")
+	b.WriteString(e.Cond.condString())
+	b.WriteString(")")
+	b.WriteString(exprsString(e.Then))
+	if e.Else != nil {
+		b.WriteString("#else")
+		b.WriteString(exprsString(e.Else))
+	}
+	b.WriteString("#end")
+	return b.String()
+}
+
+func exprsString(exprs []Expr) string {
+	b := new(strings.Builder)
+	for _, e := range exprs {
+		b.WriteString(e.String())
+	}
+	return b.String()
+}
+
+func argsString(args []any) string {
+	s := make([]string, 0, len(args))
+	for _, arg := range args {
+		s = append(s, argString(arg))
+	}
+	return strings.Join(s, ", ")
+}
+
+func argString(arg any) string {
+	switch arg := arg.(type) {
+	case nil:
+		return "nil"
+	case string:
+		return "'" + strings.ReplaceAll(arg, "'", "''") + "'"
+	case float64:
+		return formatFloat(arg)
+	case float32:
+		return formatFloat(float64(arg))
+	case Expr:
+		return arg.String()
+	default:
+		return fmt.Sprint(arg)
+	}
+}
+
+func formatFloat(f float64) string {
+	if f == math.Trunc(f) && math.Abs(f) < 1e15 {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
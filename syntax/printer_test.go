@@ -0,0 +1,144 @@
+package syntax
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestString(t *testing.T) {
+	testCases := []struct {
+		raw  string
+		want string // the canonical form, empty if same as raw
+	}{
+		{raw: "SELECT * FROM foo WHERE id = $1 AND name = $2"},
+		{raw: "a IN (?, ?, ?)"},
+		{raw: "#f1 #fragment12 #arg #join('#f', ' AND ', 2)"},
+		{raw: "#join('#f = ''#arg''', ', ')"},
+		{raw: "#a(1.5, -2, true, false, nil, 'x')"},
+		{raw: "#f(user_filter)", want: "#f('user_filter')"},
+		{raw: "#f( 1 ,'a' )", want: "#f(1, 'a')"},
+		{raw: "#if( f1 ,'x' )a#end", want: "#if(f1, 'x')a#end"},
+		{raw: "#a( 1.50 , null )", want: "#a(1.5, nil)"},
+		{raw: "#a(#f1, #arg, #b(#f(2), 'x'))"},
+		{raw: "a#if(f1)b#else c#end d"},
+		{raw: "#if(arg, 2) x#end#if(f1, 'y')#else#end"},
+		{raw: "#if(debug)#if(has, 'x', 1)$1#end#end"},
+		{raw: "data \\?| $1 AND \\#f1 AND data #>> '{a}'"},
+		{raw: "'$1 #f1' -- ? #f1\n/* $2 */ $$ ? $$ ?"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.raw, func(t *testing.T) {
+			c, err := Parse(tc.raw)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := tc.want
+			if want == "" {
+				want = tc.raw
+			}
+			got := c.String()
+			if got != want {
+				t.Fatalf("got %q, want %q", got, want)
+			}
+			// the canonical form is stable
+			c2, err := Parse(got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got2 := c2.String(); got2 != got {
+				t.Errorf("reprinted: got %q, want %q", got2, got)
+			}
+		})
+	}
+}
+
+func TestSpans(t *testing.T) {
+	raw := "SELECT $1, \\?x #f1 #arg#if(f2, 'a')#a(#f(1), #b) #else $2#end"
+	c, err := Parse(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var check func(exprs []Expr)
+	check = func(exprs []Expr) {
+		for _, e := range exprs {
+			span := e.Span()
+			if got := raw[span.Start:span.End]; got != e.String() {
+				t.Errorf("%T: span [%d:%d] is %q, want %q", e, span.Start, span.End, got, e.String())
+			}
+			switch e := e.(type) {
+			case *FuncCallExpr:
+				for _, arg := range e.Args {
+					if arg, ok := arg.(Expr); ok {
+						check([]Expr{arg})
+					}
+				}
+			case *IfExpr:
+				if got, want := raw[e.Cond.Span().Start:e.Cond.Span().End], e.Cond.condString(); got != want {
+					t.Errorf("cond span is %q, want %q", got, want)
+				}
+				check(e.Then)
+				check(e.Else)
+			}
+		}
+	}
+	check(c.ExprList)
+}
+
+func TestRewrite(t *testing.T) {
+	c, err := Parse("a = ? AND #if(f1)b IN (#join('#arg', ', '))#else #g(#f2, #arg)#end")
+	if err != nil {
+		t.Fatal(err)
+	}
+	Rewrite(c, func(e Expr) Expr {
+		switch e := e.(type) {
+		case *BindVarExpr:
+			e.Type = Dollar
+		case *FuncCallExpr:
+			if e.Name == "f" {
+				e.Name = "fragment"
+			}
+		case *FuncExpr:
+			// remove the function values
+			return nil
+		}
+		return e
+	})
+	want := "a = $1 AND #if(fragment1)b IN (#join('#arg', ', '))#else #g(#fragment2)#end"
+	if got := c.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func ExampleRewrite() {
+	c, err := Parse("SELECT * FROM foo WHERE a = ? AND b = ?")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	// convert to the Dollar style
+	Rewrite(c, func(e Expr) Expr {
+		if b, ok := e.(*BindVarExpr); ok {
+			b.Type = Dollar
+		}
+		return e
+	})
+	fmt.Println(c)
+	// Output:
+	// SELECT * FROM foo WHERE a = $1 AND b = $2
+}
+
+func ExampleClause_Format() {
+	c, err := Parse("a = $1#if(f1) AND #f1#end")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Printf("%+v", c)
+	// Output:
+	// *syntax.PlainExpr 1:1 [0:4] "a = "
+	// *syntax.BindVarExpr 1:5 [4:6] "$1"
+	// *syntax.IfExpr 1:7 [6:25] "#if(f1) AND #f1#end"
+	// 	*syntax.FuncCallExpr 1:11 [10:12] "#f1"
+	// 	*syntax.PlainExpr 1:14 [13:18] " AND "
+	// 	*syntax.FuncCallExpr 1:19 [18:21] "#f1"
+}
@@ -0,0 +1,66 @@
+package syntax
+
+// Rewrite traverses the expressions of c in depth-first order, and
+// replaces each of them with the one returned by fn, which is called
+// after the children of the expression are rewritten. An expression is
+// removed if fn returns nil.
+//
+// The children are the nested calls and function values in the args of
+// a *FuncCallExpr, and the condition and branches of an *IfExpr. The
+// condition is kept if fn returns other than a *FuncCallExpr for it.
+//
+// The expressions are modified in place, and c is returned, e.g. to
+// convert the bindvars of a clause to the Dollar style:
+//
+//	syntax.Rewrite(c, func(e syntax.Expr) syntax.Expr {
+//		if b, ok := e.(*syntax.BindVarExpr); ok {
+//			b.Type = syntax.Dollar
+//		}
+//		return e
+//	})
+func Rewrite(c *Clause, fn func(Expr) Expr) *Clause {
+	c.ExprList = rewriteList(c.ExprList, fn)
+	return c
+}
+
+func rewriteList(exprs []Expr, fn func(Expr) Expr) []Expr {
+	if exprs == nil {
+		return nil
+	}
+	r := exprs[:0]
+	for _, e := range exprs {
+		if e = rewrite(e, fn); e != nil {
+			r = append(r, e)
+		}
+	}
+	return r
+}
+
+func rewrite(e Expr, fn func(Expr) Expr) Expr {
+	switch e := e.(type) {
+	case *FuncCallExpr:
+		rewriteArgs(e, fn)
+	case *IfExpr:
+		rewriteArgs(e.Cond, fn)
+		if cond, ok := fn(e.Cond).(*FuncCallExpr); ok && cond != nil {
+			e.Cond = cond
+		}
+		e.Then = rewriteList(e.Then, fn)
+		e.Else = rewriteList(e.Else, fn)
+	}
+	return fn(e)
+}
+
+func rewriteArgs(call *FuncCallExpr, fn func(Expr) Expr) {
+	args := call.Args[:0]
+	for _, arg := range call.Args {
+		if e, ok := arg.(Expr); ok {
+			if e = rewrite(e, fn); e == nil {
+				continue
+			}
+			arg = e
+		}
+		args = append(args, arg)
+	}
+	call.Args = args
+}
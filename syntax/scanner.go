@@ -1,6 +1,9 @@
 package syntax
 
-import "strconv"
+import (
+	"strconv"
+	"strings"
+)
 
 // scanFn is the lexical scan function
 type scanFn func(*scanner) scanFn
@@ -12,6 +15,18 @@ type scanner struct {
 	tokens []*token
 	token  *token
 	state  scanFn
+	depth  int // depth of the nested function args
+
+	backslashEscapes bool // backslashes escape characters in all quoted strings
+	escaped          bool // the next plain token starts with an escaped character
+}
+
+// afterFunc returns the scan function after a function name or call.
+func (s *scanner) afterFunc() scanFn {
+	if s.depth > 0 {
+		return scanFuncArgs
+	}
+	return scanPlain
 }
 
 func newScanner(input string) *scanner {
@@ -23,7 +38,7 @@ func newScanner(input string) *scanner {
 }
 
 func (s *scanner) emitToken(t TokenType, kind litKind, bad bool) {
-	s.tokens = append(s.tokens, &token{
+	tk := &token{
 		typ:   t,
 		kind:  kind,
 		bad:   bad,
@@ -31,7 +46,18 @@ func (s *scanner) emitToken(t TokenType, kind litKind, bad bool) {
 		end:   s.current.offset,
 		pos:   s.start.Pos,
 		lit:   s.input[s.start.offset:s.current.offset],
-	})
+	}
+	if t == _Plain {
+		tk.escaped = s.escaped
+		s.escaped = false
+	}
+	s.tokens = append(s.tokens, tk)
+}
+
+// backup pushes back current token, which will be returned
+// by the next call of NextToken.
+func (s *scanner) backup() {
+	s.tokens = append([]*token{s.token}, s.tokens...)
 }
 
 // NextToken finds the next token
@@ -52,6 +78,9 @@ func scanPlain(s *scanner) scanFn {
 	for r := s.rune; r != EOF; r = s.Next() {
 		switch r {
 		case '$', '?':
+			if r == '$' && s.skipDollarQuoted() {
+				continue
+			}
 			if s.Peek() == r {
 				s.Next()
 				continue
@@ -61,10 +90,33 @@ func scanPlain(s *scanner) scanFn {
 			}
 			return scanRef
 		case '#':
+			if p := s.Peek(); p != '_' && !('a' <= p|0x20 && p|0x20 <= 'z') {
+				// not a function, e.g.: the operators #> and #-
+				continue
+			}
 			if s.current.offset > s.start.offset {
 				s.emitToken(_Plain, _StringLit, false)
 			}
 			return scanFunc
+		case '\\':
+			switch s.Peek() {
+			case '?', '#', '$':
+				// escaped, drop the backslash
+				if s.current.offset > s.start.offset {
+					s.emitToken(_Plain, _StringLit, false)
+				}
+				s.Next()
+				s.StartToken()
+				s.escaped = true
+			}
+		case '-':
+			if s.Peek() == '-' {
+				s.skipLineComment()
+			}
+		case '/':
+			if s.Peek() == '*' {
+				s.skipBlockComment()
+			}
 		case '\'', '"', '`':
 			return scanQuotedPlain
 		}
@@ -100,20 +152,11 @@ func scanIndex(s *scanner) scanFn {
 }
 
 func scanQuotedPlain(s *scanner) scanFn {
-	quoter := s.rune
-	for r := s.Next(); r != EOF; r = s.Next() {
-		if r == quoter {
-			if quoter == '\'' && s.Peek() == '\'' {
-				s.Next()
-				continue
-			}
-			s.Next()
-			s.emitToken(_Plain, _StringLit, false)
-			return scanPlain
-		}
-	}
-	// EOF
-	s.emitToken(_Plain, _StringLit, true)
+	offset := s.current.offset
+	backslash := s.backslashEscapes || s.rune == '\'' && isEscapeStringPrefix(s.input, offset)
+	end, ok := quotedEnd(s.input[offset:], backslash)
+	s.skipTo(offset + end)
+	s.emitToken(_Plain, _StringLit, !ok)
 	return scanPlain
 }
 
@@ -130,7 +173,7 @@ func scanFuncName(s *scanner) scanFn {
 		s.Next()
 	}
 	if !s.Advanced() {
-		return scanPlain
+		return s.afterFunc()
 	}
 	s.emitToken(_Name, _StringLit, false)
 	s.StartToken()
@@ -139,14 +182,15 @@ func scanFuncName(s *scanner) scanFn {
 	}
 	if s.Advanced() {
 		s.emitToken(_Literal, _NumberLit, false)
-		return scanPlain
+		return s.afterFunc()
 	}
 	if s.rune == '(' {
 		s.Next()
 		s.emitToken(_Lparen, _StringLit, false)
+		s.depth++
 		return scanFuncArgs
 	}
-	return scanPlain
+	return s.afterFunc()
 }
 
 func scanFuncArgs(s *scanner) scanFn {
@@ -162,15 +206,19 @@ func scanFuncArgs(s *scanner) scanFn {
 		case ')':
 			s.Next()
 			s.emitToken(_Rparen, _StringLit, false)
-			return scanPlain
+			s.depth--
+			return s.afterFunc()
 		case '\'':
 			return scanFuncArgQuoted
+		case '#':
+			// nested function call or value
+			return scanFunc
 		default:
 			for r != EOF && r != ',' && r != ')' {
 				r = s.Next()
 			}
 			if s.Advanced() {
-				fragment := s.input[s.start.offset:s.current.offset]
+				fragment := strings.TrimSpace(s.input[s.start.offset:s.current.offset])
 				if fragment == "true" || fragment == "false" {
 					s.emitToken(_Literal, _BoolLit, false)
 					return scanFuncArgs
@@ -183,7 +231,7 @@ func scanFuncArgs(s *scanner) scanFn {
 					s.emitToken(_Literal, _NumberLit, false)
 					return scanFuncArgs
 				}
-				s.emitToken(_Name, _StringLit, true)
+				s.emitToken(_Name, _StringLit, !isIdent(fragment))
 			}
 			return scanFuncArgs
 		}
@@ -208,3 +256,45 @@ func scanFuncArgQuoted(s *scanner) scanFn {
 	s.emitToken(_Literal, _StringLit, true)
 	return scanPlain
 }
+
+// skipDollarQuoted skips the dollar-quoted string starting at current
+// position, e.g.: $$a$$, $tag$a$tag$, and leaves the scanner at the last
+// '$'. It reports false and moves nothing if there is no such string.
+func (s *scanner) skipDollarQuoted() bool {
+	end := dollarQuotedEnd(s.input[s.current.offset:])
+	if end == 0 {
+		return false
+	}
+	s.skipTo(s.current.offset + end - 1)
+	return true
+}
+
+// skipLineComment skips the comment starting with '--', and leaves the
+// scanner at the last character before the line break.
+func (s *scanner) skipLineComment() {
+	s.skipTo(s.current.offset + lineCommentEnd(s.input[s.current.offset:]) - 1)
+}
+
+// skipBlockComment skips the comment between '/*' and '*/', which can
+// be nested, and leaves the scanner at the last '/'.
+func (s *scanner) skipBlockComment() {
+	s.skipTo(s.current.offset + blockCommentEnd(s.input[s.current.offset:]) - 1)
+}
+
+// skipTo moves the scanner to the offset.
+func (s *scanner) skipTo(offset int) {
+	for s.current.offset < offset && s.rune != EOF {
+		s.Next()
+	}
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || 'a' <= c|0x20 && c|0x20 <= 'z' || '0' <= c && c <= '9'
+}
+
+// isIdent reports whether s consists of letters and underscores,
+// optionally followed by digits, e.g.: f, f1, my_func.
+func isIdent(s string) bool {
+	_, _, ok := splitCondName(s)
+	return ok
+}
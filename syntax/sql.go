@@ -0,0 +1,193 @@
+package syntax
+
+import "strings"
+
+// SQLTokenKind is the kind of SQLToken.
+type SQLTokenKind int
+
+// SQL token kinds.
+const (
+	SQLSpace       SQLTokenKind = iota
+	SQLWord                     // keywords, identifiers, numbers and bindvars like $1
+	SQLQuoted                   // string literals, quoted identifiers and dollar-quoted strings
+	SQLComment                  // /* */
+	SQLLineComment              // --
+	SQLOpen                     // (
+	SQLClose                    // )
+	SQLComma                    // ,
+	SQLSemicolon                // ;
+	SQLOther                    // operators and bindvars like ?
+)
+
+// SQLToken is a token of the built SQL.
+type SQLToken struct {
+	Kind SQLTokenKind
+	Text string
+}
+
+// TokenizeSQL splits the built query into tokens, which are joined back
+// to the query. It recognizes the quoted strings, dollar-quoted strings
+// and comments the same way as Parse does, and only the
+// WithBackslashEscapes option applies.
+func TokenizeSQL(query string, options ...ParseOption) []*SQLToken {
+	opts := &parseOptions{}
+	for _, opt := range options {
+		opt(opts)
+	}
+	var tokens []*SQLToken
+	i := 0
+	emit := func(kind SQLTokenKind, end int) {
+		tokens = append(tokens, &SQLToken{Kind: kind, Text: query[i:end]})
+		i = end
+	}
+	for i < len(query) {
+		s := query[i:]
+		c := s[0]
+		switch {
+		case isSpaceByte(c):
+			end := 1
+			for end < len(s) && isSpaceByte(s[end]) {
+				end++
+			}
+			emit(SQLSpace, i+end)
+		case strings.HasPrefix(s, "--"):
+			emit(SQLLineComment, i+lineCommentEnd(s))
+		case strings.HasPrefix(s, "/*"):
+			emit(SQLComment, i+blockCommentEnd(s))
+		case c == '\'' || c == '"' || c == '`':
+			end, _ := quotedEnd(s, opts.backslashEscapes)
+			emit(SQLQuoted, i+end)
+		case c == '$' && dollarQuotedEnd(s) > 0:
+			emit(SQLQuoted, i+dollarQuotedEnd(s))
+		case c == '(':
+			emit(SQLOpen, i+1)
+		case c == ')':
+			emit(SQLClose, i+1)
+		case c == ',':
+			emit(SQLComma, i+1)
+		case c == ';':
+			emit(SQLSemicolon, i+1)
+		case isSQLWordByte(c):
+			end := 1
+			for end < len(s) && isSQLWordByte(s[end]) {
+				end++
+			}
+			if end < len(s) && s[end] == '\'' && isEscapeStringPrefix(query, i+end) {
+				// E'...'
+				n, _ := quotedEnd(s[end:], true)
+				emit(SQLQuoted, i+end+n)
+				continue
+			}
+			emit(SQLWord, i+end)
+		default:
+			end := 1
+			for end < len(s) && isSQLOperatorByte(s[end]) {
+				end++
+			}
+			emit(SQLOther, i+end)
+		}
+	}
+	return tokens
+}
+
+// quotedEnd returns the end of the string quoted by the first byte of s,
+// and whether it's terminated. The backslashes escape characters in the
+// quoted strings other than `...` if backslash is true.
+func quotedEnd(s string, backslash bool) (int, bool) {
+	quote := s[0]
+	backslash = backslash && quote != '`'
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if backslash {
+				i++
+			}
+		case quote:
+			if i+1 < len(s) && s[i+1] == quote {
+				i++
+				continue
+			}
+			return i + 1, true
+		}
+	}
+	return len(s), false
+}
+
+// isEscapeStringPrefix reports whether the quote at i of the input
+// starts a PostgreSQL escape string, e.g.: E'a\'b'.
+func isEscapeStringPrefix(input string, i int) bool {
+	if i < 1 || input[i-1]|0x20 != 'e' {
+		return false
+	}
+	return i < 2 || !isSQLWordByte(input[i-2])
+}
+
+// dollarQuotedEnd returns the end of the dollar-quoted string at the
+// start of s, e.g.: $$a$$, $tag$a$tag$, or 0 if it's not one.
+func dollarQuotedEnd(s string) int {
+	j := 1
+	for j < len(s) && isIdentByte(s[j]) {
+		if j == 1 && '0' <= s[j] && s[j] <= '9' {
+			// bindvar, e.g.: $1
+			return 0
+		}
+		j++
+	}
+	if j >= len(s) || s[j] != '$' {
+		return 0
+	}
+	tag := s[:j+1]
+	end := strings.Index(s[j+1:], tag)
+	if end < 0 {
+		return 0
+	}
+	return j + 1 + end + len(tag)
+}
+
+// lineCommentEnd returns the end of the comment starting with '--' at
+// the start of s, which excludes the line break.
+func lineCommentEnd(s string) int {
+	end := strings.IndexByte(s, '\n')
+	if end < 0 {
+		return len(s)
+	}
+	return end
+}
+
+// blockCommentEnd returns the end of the comment between '/*' and '*/'
+// at the start of s, which can be nested.
+func blockCommentEnd(s string) int {
+	depth := 0
+	for i := 0; i < len(s)-1; i++ {
+		switch s[i : i+2] {
+		case "/*":
+			depth++
+			i++
+		case "*/":
+			depth--
+			i++
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return len(s)
+}
+
+func isSpaceByte(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v'
+}
+
+func isSQLWordByte(c byte) bool {
+	return c == '$' || c == '.' || c >= 0x80 || isIdentByte(c)
+}
+
+func isSQLOperatorByte(c byte) bool {
+	switch c {
+	case '?':
+		// keep bindvars separated, e.g. ??
+		return false
+	}
+	return !isSpaceByte(c) && !isSQLWordByte(c) &&
+		c != '(' && c != ')' && c != ',' && c != ';' && c != '\'' && c != '"' && c != '`'
+}
@@ -0,0 +1,67 @@
+package syntax
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTokenizeSQL(t *testing.T) {
+	testCases := []struct {
+		query   string
+		options []ParseOption
+		want    []SQLToken
+	}{
+		{
+			query: "SELECT a,b FROM t WHERE id IN ($1, ?)",
+			want: []SQLToken{
+				{SQLWord, "SELECT"}, {SQLSpace, " "}, {SQLWord, "a"}, {SQLComma, ","}, {SQLWord, "b"},
+				{SQLSpace, " "}, {SQLWord, "FROM"}, {SQLSpace, " "}, {SQLWord, "t"},
+				{SQLSpace, " "}, {SQLWord, "WHERE"}, {SQLSpace, " "}, {SQLWord, "id"},
+				{SQLSpace, " "}, {SQLWord, "IN"}, {SQLSpace, " "}, {SQLOpen, "("}, {SQLWord, "$1"},
+				{SQLComma, ","}, {SQLSpace, " "}, {SQLOther, "?"}, {SQLClose, ")"},
+			},
+		},
+		{
+			query: `'a''b' "c""d" E'e\'f' $x$g$x$`,
+			want: []SQLToken{
+				{SQLQuoted, `'a''b'`}, {SQLSpace, " "}, {SQLQuoted, `"c""d"`}, {SQLSpace, " "},
+				{SQLQuoted, `E'e\'f'`}, {SQLSpace, " "}, {SQLQuoted, "$x$g$x$"},
+			},
+		},
+		{
+			query: `'a\'b' -- c`,
+			want: []SQLToken{
+				{SQLQuoted, `'a\'`}, {SQLWord, "b"}, {SQLQuoted, "' -- c"},
+			},
+		},
+		{
+			query:   `'a\'b' -- c`,
+			options: []ParseOption{WithBackslashEscapes()},
+			want: []SQLToken{
+				{SQLQuoted, `'a\'b'`}, {SQLSpace, " "}, {SQLLineComment, "-- c"},
+			},
+		},
+		{
+			query: "a /* b /* c */ */;",
+			want: []SQLToken{
+				{SQLWord, "a"}, {SQLSpace, " "}, {SQLComment, "/* b /* c */ */"}, {SQLSemicolon, ";"},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		tokens := TokenizeSQL(tc.query, tc.options...)
+		got := make([]SQLToken, 0, len(tokens))
+		texts := make([]string, 0, len(tokens))
+		for _, t := range tokens {
+			got = append(got, *t)
+			texts = append(texts, t.Text)
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: got %v, want %v", tc.query, got, tc.want)
+		}
+		if joined := strings.Join(texts, ""); joined != tc.query {
+			t.Errorf("%s: joined back to %s", tc.query, joined)
+		}
+	}
+}
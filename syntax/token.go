@@ -1,13 +1,16 @@
 package syntax
 
 type token struct {
-	typ   TokenType
-	lit   string
-	bad   bool
-	kind  litKind
-	start int
-	end   int
-	pos   Pos
+	typ  TokenType
+	lit  string
+	bad  bool
+	kind litKind
+	// escaped reports whether the first character of the plain
+	// text is escaped by a backslash, e.g.: \?
+	escaped bool
+	start   int
+	end     int
+	pos     Pos
 }
 
 // TokenType is the type of token.
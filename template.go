@@ -0,0 +1,199 @@
+package sqlf
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/qjebbs/go-sqlf/v2/syntax"
+)
+
+// Param is the placeholder of an arg whose value is supplied later,
+// when executing a Template compiled from the builder.
+//
+// Params with a same name share the same value, and with syntax.Dollar
+// style, they share the same bindvar.
+type Param string
+
+// Params is the values for the Params of a Template, keyed by their names.
+type Params map[string]any
+
+// Template is a query compiled from a QueryBuilder, which holds the built
+// query of every BindVarStyle, and the slots of the args.
+//
+// Executing a Template is only a matter of filling the slots, without any
+// parsing, reflection or string building, which is useful for queries with
+// a fixed shape, but different arg values.
+//
+//	tmpl, err := sqlf.Compile(sqlf.Fa(
+//		"SELECT * FROM foo WHERE id = $1 AND status = $2",
+//		sqlf.Param("id"), "active",
+//	))
+//	// ...
+//	query, args, err := tmpl.Query(syntax.Dollar, sqlf.Params{"id": 1})
+//	// SELECT * FROM foo WHERE id = $1 AND status = $2
+//	// [1 active]
+//
+// The shape of the query is determined at compile time, so a Param cannot
+// be used where the query shape depends on its value. For example, it's
+// fine to #join('#arg', ', ') a list of Params, but the list length is fixed,
+// and Compile reports ErrParamValue for #if(arg1) of a Param.
+// Executing with a slice or array value (except []byte and driver.Valuer)
+// is reported as an error, since it usually means a placeholder list is
+// expected, which requires a Template for each list length.
+type Template struct {
+	queries map[syntax.BindVarStyle]*compiledQuery
+	params  []string // sorted names of the params
+}
+
+type compiledQuery struct {
+	query string
+	slots []slot
+}
+
+// slot is an arg slot of a compiled query, which is filled
+// with value, or the value of the param if it's not empty.
+type slot struct {
+	param Param
+	value any
+}
+
+var compileStyles = []syntax.BindVarStyle{syntax.Dollar, syntax.Question}
+
+// Compile compiles the builder into a Template.
+//
+// Use Param as the args whose values are supplied when executing the Template,
+// all other args are compiled as constants.
+func Compile(b QueryBuilder) (*Template, error) {
+	if b == nil {
+		return nil, fmt.Errorf("nil builder")
+	}
+	t := &Template{
+		queries: make(map[syntax.BindVarStyle]*compiledQuery, len(compileStyles)),
+	}
+	params := make(map[string]bool)
+	for _, style := range compileStyles {
+		query, args, err := b.BuildQuery(style)
+		if err != nil {
+			return nil, fmt.Errorf("compile: %w", err)
+		}
+		q := &compiledQuery{
+			query: query,
+			slots: make([]slot, len(args)),
+		}
+		for i, arg := range args {
+			if p, ok := arg.(Param); ok {
+				if p == "" {
+					return nil, fmt.Errorf("compile: empty param name of arg %d", i+1)
+				}
+				q.slots[i] = slot{param: p}
+				params[string(p)] = true
+				continue
+			}
+			q.slots[i] = slot{value: arg}
+		}
+		t.queries[style] = q
+	}
+	for name := range params {
+		t.params = append(t.params, name)
+	}
+	sort.Strings(t.params)
+	return t, nil
+}
+
+// Params returns the sorted names of the params of the template.
+func (t *Template) Params() []string {
+	return append([]string(nil), t.params...)
+}
+
+// Query returns the compiled query of the bindVarStyle,
+// and the args filled with the params.
+func (t *Template) Query(bindVarStyle syntax.BindVarStyle, params Params) (query string, args []any, err error) {
+	q, ok := t.queries[bindVarStyle]
+	if !ok {
+		return "", nil, fmt.Errorf("unsupported bindvar style: %d", bindVarStyle)
+	}
+	if err := t.checkParams(params); err != nil {
+		return "", nil, err
+	}
+	args = make([]any, len(q.slots))
+	for i, s := range q.slots {
+		if s.param == "" {
+			args[i] = s.value
+			continue
+		}
+		v := params[string(s.param)]
+		if IsList(v) {
+			return "", nil, fmt.Errorf(
+				"param %q: %T value changes the query shape, compile a template for each list length instead",
+				s.param, v,
+			)
+		}
+		args[i] = v
+	}
+	return q.query, args, nil
+}
+
+// Bind returns a QueryBuilder which builds the template with the params.
+func (t *Template) Bind(params Params) QueryBuilder {
+	return &boundTemplate{
+		template: t,
+		params:   params,
+	}
+}
+
+func (t *Template) checkParams(params Params) error {
+	missing := make([]string, 0)
+	for _, name := range t.params {
+		if _, ok := params[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing params: %s", strings.Join(missing, ", "))
+	}
+	if len(params) == len(t.params) {
+		return nil
+	}
+	unknown := make([]string, 0)
+	for name := range params {
+		i := sort.SearchStrings(t.params, name)
+		if i == len(t.params) || t.params[i] != name {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	return fmt.Errorf("unknown params: %s", strings.Join(unknown, ", "))
+}
+
+var _ QueryBuilder = (*boundTemplate)(nil)
+
+type boundTemplate struct {
+	template *Template
+	params   Params
+}
+
+// BuildQuery implements QueryBuilder
+func (b *boundTemplate) BuildQuery(bindVarStyle syntax.BindVarStyle) (query string, args []any, err error) {
+	return b.template.Query(bindVarStyle, b.params)
+}
+
+// IsList reports whether v is a list of values, that is a slice or an
+// array, except the ones of bytes and driver.Valuer, which are single
+// values. It's the lists expanded by SliceExpansion.
+func IsList(v any) bool {
+	if v == nil {
+		return false
+	}
+	if _, ok := v.(driver.Valuer); ok {
+		return false
+	}
+	t := reflect.TypeOf(v)
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		return t.Elem().Kind() != reflect.Uint8
+	}
+	return false
+}
@@ -0,0 +1,160 @@
+package sqlf_test
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/qjebbs/go-sqlf/v2"
+	"github.com/qjebbs/go-sqlf/v2/sqlb"
+	"github.com/qjebbs/go-sqlf/v2/syntax"
+)
+
+func TestTemplate(t *testing.T) {
+	t.Parallel()
+	foo := sqlb.NewTableAliased("foo", "f")
+	testCases := []struct {
+		name      string
+		builder   sqlf.QueryBuilder
+		params    sqlf.Params
+		style     syntax.BindVarStyle
+		wantQuery string
+		wantArgs  []any
+		wantErr   bool
+	}{
+		{
+			name: "params and constants",
+			builder: sqlf.Fa(
+				"SELECT * FROM foo WHERE id = $1 AND status = $2",
+				sqlf.Param("id"), "active",
+			),
+			params:    sqlf.Params{"id": 1},
+			style:     syntax.Dollar,
+			wantQuery: "SELECT * FROM foo WHERE id = $1 AND status = $2",
+			wantArgs:  []any{1, "active"},
+		},
+		{
+			name: "shared param",
+			builder: sqlf.Fa(
+				"a = $1 OR b = $2 OR c = $3",
+				sqlf.Param("x"), sqlf.Param("y"), sqlf.Param("x"),
+			),
+			params:    sqlf.Params{"x": 1, "y": 2},
+			style:     syntax.Dollar,
+			wantQuery: "a = $1 OR b = $2 OR c = $1",
+			wantArgs:  []any{1, 2},
+		},
+		{
+			name: "shared param question",
+			builder: sqlf.Fa(
+				"a = $1 OR b = $2 OR c = $3",
+				sqlf.Param("x"), sqlf.Param("y"), sqlf.Param("x"),
+			),
+			params:    sqlf.Params{"x": 1, "y": 2},
+			style:     syntax.Question,
+			wantQuery: "a = ? OR b = ? OR c = ?",
+			wantArgs:  []any{1, 2, 1},
+		},
+		{
+			name: "query builder",
+			builder: sqlb.NewQueryBuilder().
+				Select(foo.Column("*")).
+				From(foo).
+				Where2(foo.Column("id"), "=", sqlf.Param("id")).
+				WhereIn(foo.Column("type"), []any{1, 2}),
+			params:    sqlf.Params{"id": 3},
+			style:     syntax.Dollar,
+			wantQuery: "SELECT f.* FROM foo AS f WHERE f.id=$1 AND f.type IN ($2, $3)",
+			wantArgs:  []any{3, 1, 2},
+		},
+		{
+			name:    "missing param",
+			builder: sqlf.Fa("id = $1", sqlf.Param("id")),
+			params:  sqlf.Params{},
+			wantErr: true,
+		},
+		{
+			name:    "unknown param",
+			builder: sqlf.Fa("id = $1", sqlf.Param("id")),
+			params:  sqlf.Params{"id": 1, "name": "foo"},
+			wantErr: true,
+		},
+		{
+			name:    "list value",
+			builder: sqlf.Fa("id IN ($1)", sqlf.Param("ids")),
+			params:  sqlf.Params{"ids": []int{1, 2}},
+			wantErr: true,
+		},
+		{
+			name:      "bytes value",
+			builder:   sqlf.Fa("data = $1", sqlf.Param("data")),
+			params:    sqlf.Params{"data": []byte("foo")},
+			wantQuery: "data = $1",
+			wantArgs:  []any{[]byte("foo")},
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			tmpl, err := sqlf.Compile(tc.builder)
+			if err != nil {
+				t.Fatal(err)
+			}
+			query, args, err := tmpl.Query(tc.style, tc.params)
+			if err != nil {
+				if tc.wantErr {
+					return
+				}
+				t.Fatal(err)
+			}
+			if tc.wantErr {
+				t.Fatal("want error, got nil")
+			}
+			if query != tc.wantQuery {
+				t.Errorf("got %q, want %q", query, tc.wantQuery)
+			}
+			if !reflect.DeepEqual(args, tc.wantArgs) {
+				t.Errorf("got %v, want %v", args, tc.wantArgs)
+			}
+		})
+	}
+}
+
+func TestCompileParamCondition(t *testing.T) {
+	t.Parallel()
+	for _, b := range []sqlf.QueryBuilder{
+		sqlf.Fa("SELECT * FROM foo WHERE TRUE#if(arg1) AND name = $1#end", sqlf.Param("name")),
+		sqlf.Ff("SELECT * FROM foo#if(f1) WHERE #f1#end", sqlf.Fa("#if(arg1)name = $1#end", sqlf.Param("name"))),
+	} {
+		_, err := sqlf.Compile(b)
+		if !errors.Is(err, sqlf.ErrParamValue) {
+			t.Errorf("want ErrParamValue, got %v", err)
+		}
+	}
+}
+
+func ExampleCompile() {
+	tmpl, err := sqlf.Compile(sqlf.Fa(
+		"SELECT * FROM foo WHERE id = $1 AND status = $2",
+		sqlf.Param("id"), "active",
+	))
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	for _, id := range []int{1, 2} {
+		query, args, err := tmpl.Query(syntax.Dollar, sqlf.Params{"id": id})
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		fmt.Println(query)
+		fmt.Println(args)
+	}
+	// Output:
+	// SELECT * FROM foo WHERE id = $1 AND status = $2
+	// [1 active]
+	// SELECT * FROM foo WHERE id = $1 AND status = $2
+	// [2 active]
+}
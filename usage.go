@@ -0,0 +1,142 @@
+package sqlf
+
+// UsagePolicy is the policy for the args and fragments of a Fragment
+// which are never referenced during building.
+//
+// A #join references the items in its range only, for example,
+// #join('#arg', ', ', 3) references the args from 3 to the end, and
+// #join('#arg', ', ', 3, 5) the args 3 to 5, so that the args 1, 2
+// (and 6 to the end) should be referenced elsewhere, marked as optional
+// (see OptionalArg and OptionalFragment), or the policy of the fragment
+// should not be UsageStrict.
+type UsagePolicy int
+
+// Usage policies.
+const (
+	// UsageInherit inherits the policy of the context,
+	// which is UsageStrict if not set.
+	UsageInherit UsagePolicy = iota
+	// UsageStrict fails the build.
+	UsageStrict
+	// UsageWarn reports the *BuildError to the hook set by WithUsageHook,
+	// and continues building. It's the same as UsageIgnore if no hook is
+	// set.
+	UsageWarn
+	// UsageIgnore ignores the unused items.
+	UsageIgnore
+)
+
+// WithUsagePolicy sets the default UsagePolicy for all fragments built
+// with the context, which is overridden by Fragment.Usage.
+func WithUsagePolicy(p UsagePolicy) ContextOption {
+	return func(o *contextOptions) {
+		o.usage = p
+	}
+}
+
+// WithUsageHook sets the hook receiving the *BuildError of unused args
+// and fragments, for the fragments with the UsageWarn policy.
+func WithUsageHook(fn func(err error)) ContextOption {
+	return func(o *contextOptions) {
+		o.usageHook = fn
+	}
+}
+
+// WithUsage sets the UsagePolicy of f.
+func (f *Fragment) WithUsage(p UsagePolicy) *Fragment {
+	f.Usage = p
+	return f
+}
+
+// OptionalArg marks the arg as optional, which is allowed to be never
+// referenced, regardless of the UsagePolicy.
+//
+//	sqlf.Fa("#my_func(1)", sqlf.OptionalArg(1))
+func OptionalArg(arg any) any {
+	return optionalArg{arg}
+}
+
+// OptionalFragment marks the fragment as optional, which is allowed to be
+// never referenced, regardless of the UsagePolicy.
+func OptionalFragment(f FragmentBuilder) FragmentBuilder {
+	return &optionalFragment{f}
+}
+
+type optionalArg struct {
+	value any
+}
+
+var _ FragmentBuilder = (*optionalFragment)(nil)
+var _ Parent = (*optionalFragment)(nil)
+
+type optionalFragment struct {
+	FragmentBuilder
+}
+
+// Children implements Parent
+func (f *optionalFragment) Children() []FragmentBuilder {
+	return []FragmentBuilder{f.FragmentBuilder}
+}
+
+// argValue returns the value of the arg, unwrapping the optional one.
+func argValue(a any) (value any, optional bool) {
+	if o, ok := a.(optionalArg); ok {
+		return o.value, true
+	}
+	return a, false
+}
+
+// fragmentValue returns the fragment, unwrapping the optional one.
+func fragmentValue(f FragmentBuilder) (value FragmentBuilder, optional bool) {
+	if o, ok := f.(*optionalFragment); ok {
+		return o.FragmentBuilder, true
+	}
+	return f, false
+}
+
+// usagePolicy returns the UsagePolicy for f.
+func (c *Context) usagePolicy(f *Fragment) UsagePolicy {
+	if f != nil && f.Usage != UsageInherit {
+		return f.Usage
+	}
+	if p := c.root().usage; p != UsageInherit {
+		return p
+	}
+	return UsageStrict
+}
+
+// handleUnused handles the error of unused items of f according to
+// its UsagePolicy, it returns the error only if the build should fail.
+func (c *Context) handleUnused(f *Fragment, err error) error {
+	switch c.usagePolicy(f) {
+	case UsageIgnore:
+		return nil
+	case UsageWarn:
+		if be, ok := err.(*BuildError); ok {
+			be.Path = c.fragmentPath()
+		}
+		if hook := c.root().usageHook; hook != nil {
+			hook(err)
+		}
+		return nil
+	default:
+		return err
+	}
+}
+
+// fragmentPath returns the path of the ancestor fragments of current one.
+func (c *Context) fragmentPath() []ErrorFrame {
+	var r []ErrorFrame
+	current := true
+	for ctx := c; ctx != nil; ctx = ctx.parent {
+		if ctx.frag == nil || ctx.frag.Fragment == nil {
+			continue
+		}
+		if current {
+			current = false
+			continue
+		}
+		r = append([]ErrorFrame{{Raw: ctx.frag.Fragment.Raw}}, r...)
+	}
+	return r
+}
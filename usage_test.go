@@ -0,0 +1,102 @@
+package sqlf_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/qjebbs/go-sqlf/v2"
+	"github.com/qjebbs/go-sqlf/v2/syntax"
+)
+
+func TestUsagePolicy(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		name      string
+		options   []sqlf.ContextOption
+		fragment  *sqlf.Fragment
+		want      string
+		wantErr   bool
+		wantWarns int
+	}{
+		{
+			name:     "strict by default",
+			fragment: sqlf.Fa("a", 1),
+			wantErr:  true,
+		},
+		{
+			name:     "fragment ignore",
+			fragment: sqlf.Ff("#f1", sqlf.Fa("a", 1).WithUsage(sqlf.UsageIgnore)),
+			want:     "a",
+		},
+		{
+			name:     "context ignore",
+			options:  []sqlf.ContextOption{sqlf.WithUsagePolicy(sqlf.UsageIgnore)},
+			fragment: sqlf.Ff("#f1", sqlf.Fa("a", 1)),
+			want:     "a",
+		},
+		{
+			name:     "fragment overrides context",
+			options:  []sqlf.ContextOption{sqlf.WithUsagePolicy(sqlf.UsageIgnore)},
+			fragment: sqlf.Ff("#f1", sqlf.Fa("a", 1).WithUsage(sqlf.UsageStrict)),
+			wantErr:  true,
+		},
+		{
+			name:      "warn",
+			options:   []sqlf.ContextOption{sqlf.WithUsagePolicy(sqlf.UsageWarn)},
+			fragment:  sqlf.Ff("#f1", sqlf.Fa("a", 1), sqlf.F("b")),
+			want:      "a",
+			wantWarns: 2,
+		},
+		{
+			name: "optional",
+			fragment: sqlf.Fa("#join('#arg', ', ', 2)", sqlf.OptionalArg(1), 2, 3).
+				WithFragments(sqlf.OptionalFragment(sqlf.F("a"))),
+			want: "$1, $2",
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			warns := 0
+			options := append(tc.options, sqlf.WithUsageHook(func(err error) {
+				var ue *sqlf.UnusedError
+				if !errors.As(err, &ue) {
+					t.Errorf("want *sqlf.UnusedError, got %v", err)
+				}
+				warns++
+			}))
+			ctx := sqlf.NewContext(syntax.Dollar, options...)
+			got, err := tc.fragment.BuildFragment(ctx)
+			if err != nil {
+				if tc.wantErr {
+					return
+				}
+				t.Fatal(err)
+			}
+			if tc.wantErr {
+				t.Fatal("want error, got nil")
+			}
+			if got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+			if warns != tc.wantWarns {
+				t.Errorf("got %d warnings, want %d", warns, tc.wantWarns)
+			}
+			if err := sqlf.ValidateContext(ctx, tc.fragment); err != nil {
+				t.Errorf("validate: %v", err)
+			}
+		})
+	}
+}
+
+func TestUsageWarnWithoutHook(t *testing.T) {
+	t.Parallel()
+	ctx := sqlf.NewContext(syntax.Dollar, sqlf.WithUsagePolicy(sqlf.UsageWarn))
+	got, err := sqlf.Ff("#f1", sqlf.Fa("a", 1)).BuildFragment(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "a" {
+		t.Errorf("got %q, want %q", got, "a")
+	}
+}
@@ -10,7 +10,7 @@ import (
 	"github.com/qjebbs/go-sqlf/v2/util"
 )
 
-func ExampleArgs() {
+func ExampleArgsFlatted() {
 	print := func(v any) {
 		fmt.Printf("%#v\n", v)
 	}
@@ -29,14 +29,14 @@ func ExampleArgs() {
 
 func ExampleInterpolate() {
 	query := "SELECT * FROM foo WHERE status = ? AND created_at > ?"
-	args := []any{"ok", time.Unix(0, 0)}
+	args := []any{"ok", time.Unix(0, 0).UTC()}
 	interpolated, err := util.Interpolate(query, args, util.WithTimeFormat("2006-01-02 15:04:05"))
 	if err != nil {
 		panic(err)
 	}
 	fmt.Println(interpolated)
 	// Output:
-	// SELECT * FROM foo WHERE status = 'ok' AND created_at > '1970-01-01 08:00:00'
+	// SELECT * FROM foo WHERE status = 'ok' AND created_at > '1970-01-01 00:00:00'
 }
 
 func ExampleCountBuilder() {
@@ -118,3 +118,28 @@ func ExampleScan() {
 	// Output:
 	//
 }
+
+func ExampleFormat() {
+	query := "SELECT id, name FROM users WHERE age > $1 AND id IN (SELECT user_id FROM orders WHERE total > $2) ORDER BY id"
+	fmt.Println(util.Format(query))
+	// Output:
+	// SELECT id, name
+	// FROM users
+	// WHERE age > $1
+	//   AND id IN (
+	//   SELECT user_id
+	//   FROM orders
+	//   WHERE total > $2
+	// )
+	// ORDER BY id
+}
+
+func ExampleFingerprint() {
+	a := "SELECT * FROM foo WHERE id IN ($1, $2) AND status = $3"
+	b := "select * from foo where id in ($1, $2, $3, $4)\n  and status = 'ok'"
+	fmt.Println(util.Normalize(a))
+	fmt.Println(util.Fingerprint(a) == util.Fingerprint(b))
+	// Output:
+	// select * from foo where id in (?) and status = ?
+	// true
+}
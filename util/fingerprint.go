@@ -0,0 +1,196 @@
+package util
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+
+	"github.com/qjebbs/go-sqlf/v2"
+	"github.com/qjebbs/go-sqlf/v2/syntax"
+)
+
+// maxRepeatTokens is the max length of the repeated token sequences
+// collapsed by Normalize.
+const maxRepeatTokens = 64
+
+// Fingerprint returns a stable hash of the query shape, which is the hex
+// encoded 64-bit FNV-1a hash of Normalize(query). It's useful to group the
+// queries in metrics and slow-query logs.
+//
+// The options are used to tokenize the query, e.g.
+// syntax.WithBackslashEscapes() for MySQL.
+func Fingerprint(query string, options ...syntax.ParseOption) string {
+	h := fnv.New64a()
+	h.Write([]byte(Normalize(query, options...)))
+	return fmt.Sprintf("%016x", h.Sum(nil))
+}
+
+// FingerprintBuilder is like Fingerprint, but it builds query from
+// sqlf.QueryBuilder, and returns the built query and args along with the
+// fingerprint.
+func FingerprintBuilder(b sqlf.QueryBuilder, bindVarStyle syntax.BindVarStyle) (query string, args []any, fingerprint string, err error) {
+	query, args, err = b.BuildQuery(bindVarStyle)
+	if err != nil {
+		return "", nil, "", err
+	}
+	return query, args, Fingerprint(query), nil
+}
+
+// Normalize returns the normalized query shape, where
+//   - the comments are removed, and the whitespaces are collapsed;
+//   - the keywords and unquoted identifiers are lower-cased;
+//   - the bindvars, string and number literals are replaced with '?';
+//   - the repetitions of the same tokens separated by ',', AND or OR are
+//     collapsed into one, so that the IN lists of different lengths, the
+//     multi-row VALUES and the conditions joined by #join share the shape.
+//
+// The options are used to tokenize the query, see Fingerprint.
+func Normalize(query string, options ...syntax.ParseOption) string {
+	tokens := normalizeTokens(syntax.TokenizeSQL(query, options...))
+	for {
+		collapsed := collapseRepeats(tokens)
+		if len(collapsed) == len(tokens) {
+			break
+		}
+		tokens = collapsed
+	}
+	b := new(strings.Builder)
+	for i, t := range tokens {
+		if i > 0 && spaceBetween(tokens[i-1], t) {
+			b.WriteByte(' ')
+		}
+		b.WriteString(t.Text)
+	}
+	return b.String()
+}
+
+// normalizeTokens drops the spaces and comments, and normalizes the
+// literals, bindvars and words.
+func normalizeTokens(tokens []*syntax.SQLToken) []*syntax.SQLToken {
+	placeholder := &syntax.SQLToken{Kind: syntax.SQLOther, Text: "?"}
+	r := make([]*syntax.SQLToken, 0, len(tokens))
+	for i, t := range tokens {
+		switch t.Kind {
+		case syntax.SQLSpace, syntax.SQLComment, syntax.SQLLineComment:
+			continue
+		case syntax.SQLQuoted:
+			if t.Text[0] == '"' || t.Text[0] == '`' {
+				// quoted identifier
+				r = append(r, t)
+				continue
+			}
+			r = append(r, placeholder)
+		case syntax.SQLWord:
+			if i+1 < len(tokens) && isStringPrefix(t.Text) &&
+				tokens[i+1].Kind == syntax.SQLQuoted && tokens[i+1].Text[0] == '\'' {
+				// the prefix of X'..', N'..', B'..'
+				continue
+			}
+			if isBindVarWord(t.Text) || isNumberWord(t.Text) {
+				r = append(r, placeholder)
+				continue
+			}
+			text := strings.ToLower(t.Text)
+			if text == "true" || text == "false" {
+				r = append(r, placeholder)
+				continue
+			}
+			r = append(r, &syntax.SQLToken{Kind: syntax.SQLWord, Text: text})
+		default:
+			r = append(r, t)
+		}
+	}
+	return r
+}
+
+// collapseRepeats collapses 'p sep p sep p' into 'p', where sep is ',',
+// AND or OR, and p is a sequence of tokens with balanced parentheses.
+func collapseRepeats(tokens []*syntax.SQLToken) []*syntax.SQLToken {
+	r := make([]*syntax.SQLToken, 0, len(tokens))
+	for i := 0; i < len(tokens); {
+		n := 0
+		for l := 1; l <= maxRepeatTokens && i+2*l < len(tokens); l++ {
+			if !isRepeatSeparator(tokens[i+l]) || !balanced(tokens[i:i+l]) {
+				continue
+			}
+			if sameTokens(tokens[i:i+l], tokens[i+l+1:i+2*l+1]) {
+				n = l
+				break
+			}
+		}
+		if n == 0 {
+			r = append(r, tokens[i])
+			i++
+			continue
+		}
+		r = append(r, tokens[i:i+n]...)
+		sep := tokens[i+n]
+		i += n
+		for i+n < len(tokens) && sameTokens(tokens[i:i+1], []*syntax.SQLToken{sep}) &&
+			sameTokens(tokens[i+1:i+n+1], tokens[i-n:i]) {
+			i += n + 1
+		}
+	}
+	return r
+}
+
+func isRepeatSeparator(t *syntax.SQLToken) bool {
+	return t.Kind == syntax.SQLComma || t.Kind == syntax.SQLWord && (t.Text == "and" || t.Text == "or")
+}
+
+func balanced(tokens []*syntax.SQLToken) bool {
+	depth := 0
+	for _, t := range tokens {
+		switch t.Kind {
+		case syntax.SQLOpen:
+			depth++
+		case syntax.SQLClose:
+			depth--
+			if depth < 0 {
+				return false
+			}
+		}
+	}
+	return depth == 0
+}
+
+func sameTokens(a, b []*syntax.SQLToken) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Kind != b[i].Kind || a[i].Text != b[i].Text {
+			return false
+		}
+	}
+	return true
+}
+
+func isStringPrefix(s string) bool {
+	switch strings.ToLower(s) {
+	case "x", "n", "b":
+		return true
+	}
+	return false
+}
+
+// isBindVarWord reports whether s is a bindvar like $1.
+func isBindVarWord(s string) bool {
+	if len(s) < 2 || s[0] != '$' {
+		return false
+	}
+	for i := 1; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// isNumberWord reports whether s is a number literal like 1, 1.5 and .5.
+func isNumberWord(s string) bool {
+	if s[0] == '.' && len(s) > 1 {
+		s = s[1:]
+	}
+	return '0' <= s[0] && s[0] <= '9'
+}
@@ -0,0 +1,94 @@
+package util_test
+
+import (
+	"testing"
+
+	"github.com/qjebbs/go-sqlf/v2/syntax"
+	"github.com/qjebbs/go-sqlf/v2/util"
+)
+
+func TestNormalize(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		name    string
+		query   string
+		options []syntax.ParseOption
+		want    string
+	}{
+		{
+			name:  "whitespaces, case and comments",
+			query: "SELECT  id\n FROM Foo -- comment\nWHERE /* x */ \"Name\"=$1",
+			want:  `select id from foo where "Name" = ?`,
+		},
+		{
+			name:  "literals",
+			query: "SELECT * FROM foo WHERE a = 'x' AND b = 1.5 AND c = TRUE AND d = X'FF' AND e = E'it\\'s' AND f IS NULL",
+			want:  "select * from foo where a = ? and b = ? and c = ? and d = ? and e = ? and f is null",
+		},
+		{
+			name:  "in lists",
+			query: "SELECT * FROM foo WHERE id IN (?, ?, ?) AND x IN ($4,$5)",
+			want:  "select * from foo where id in (?) and x in (?)",
+		},
+		{
+			name:  "values",
+			query: "INSERT INTO foo (a, b) VALUES (?, ?), (?, ?), (?, ?)",
+			want:  "insert into foo (a, b) values (?)",
+		},
+		{
+			name:  "joined conditions",
+			query: "SELECT * FROM foo WHERE (a = $1 OR a = $2 OR a = $3) AND b > $4",
+			want:  "select * from foo where (a = ?) and b > ?",
+		},
+		{
+			name:  "operators",
+			query: "SELECT * FROM foo WHERE data ?| $1",
+			want:  "select * from foo where data ?| ?",
+		},
+		{
+			name:    "backslash escapes",
+			query:   "SELECT * FROM foo WHERE a = 'it\\'s' -- ?\nAND b = ?",
+			options: []syntax.ParseOption{syntax.WithBackslashEscapes()},
+			want:    "select * from foo where a = ? and b = ?",
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			if got := util.Normalize(tc.query, tc.options...); got != tc.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	t.Parallel()
+	same := [][]string{
+		{
+			"SELECT * FROM foo WHERE id IN (?) AND status = ?",
+			"select *\nfrom foo\nwhere id in (?, ?, ?) and status = 'ok'",
+		},
+		{
+			"SELECT * FROM foo WHERE a = $1 OR a = $2",
+			"SELECT * FROM foo WHERE a = $1 OR a = $2 OR a = $3",
+		},
+	}
+	for _, queries := range same {
+		want := util.Fingerprint(queries[0])
+		for _, q := range queries[1:] {
+			if got := util.Fingerprint(q); got != want {
+				t.Errorf("fingerprint of %q = %s, want %s", q, got, want)
+			}
+		}
+	}
+	a := util.Fingerprint("SELECT * FROM foo WHERE a = ?")
+	b := util.Fingerprint("SELECT * FROM foo WHERE b = ?")
+	if a == b {
+		t.Errorf("want different fingerprints, got %s", a)
+	}
+	if len(a) != 16 {
+		t.Errorf("want 16 hex digits, got %q", a)
+	}
+}
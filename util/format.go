@@ -0,0 +1,283 @@
+package util
+
+import (
+	"strings"
+
+	"github.com/qjebbs/go-sqlf/v2/syntax"
+)
+
+// FormatOption is the option of Format and Minify.
+type FormatOption func(*formatOptions)
+
+type formatOptions struct {
+	indent       string
+	parseOptions []syntax.ParseOption
+}
+
+// WithIndent sets the indent of Format, which is two spaces by default.
+func WithIndent(indent string) FormatOption {
+	return func(o *formatOptions) {
+		o.indent = indent
+	}
+}
+
+// WithParseOptions sets the options to tokenize the query, e.g.
+// syntax.WithBackslashEscapes() for MySQL.
+func WithParseOptions(options ...syntax.ParseOption) FormatOption {
+	return func(o *formatOptions) {
+		o.parseOptions = append(o.parseOptions, options...)
+	}
+}
+
+func applyFormatOptions(options []FormatOption) *formatOptions {
+	opts := &formatOptions{indent: "  "}
+	for _, opt := range options {
+		opt(opts)
+	}
+	return opts
+}
+
+// Format pretty-prints the built query, or the interpolated one, for
+// debug logs and golden files. It starts the clauses (SELECT, FROM, JOIN,
+// WHERE, GROUP BY, ORDER BY, UNION, etc.) and the AND / OR conditions of
+// WHERE and HAVING on new lines, and indents the subqueries.
+//
+// The string literals, quoted identifiers and comments are kept as is,
+// and the bindvars are left untouched.
+func Format(query string, options ...FormatOption) string {
+	opts := applyFormatOptions(options)
+	f := &formatter{
+		tokens: syntax.TokenizeSQL(query, opts.parseOptions...),
+		indent: opts.indent,
+	}
+	return f.format()
+}
+
+// Minify collapses the whitespaces of the query into the canonical
+// minified form, with the string literals and quoted identifiers kept
+// as is. The line comments are converted to block comments, and the
+// adjacent string literals are kept on separate lines, which PostgreSQL
+// requires to concatenate them.
+func Minify(query string, options ...FormatOption) string {
+	opts := applyFormatOptions(options)
+	tokens := syntax.TokenizeSQL(query, opts.parseOptions...)
+	b := new(strings.Builder)
+	var prev, prevCode *syntax.SQLToken
+	space := false
+	for _, t := range tokens {
+		t := t
+		if t.Kind == syntax.SQLSpace {
+			space = true
+			continue
+		}
+		if t.Kind == syntax.SQLLineComment {
+			t = lineToBlockComment(t)
+			if t.Text == "" {
+				space = true
+				continue
+			}
+		}
+		switch {
+		case isStringLiteral(prevCode) && isStringLiteral(t):
+			b.WriteByte('\n')
+		case prev != nil && (space || t.Kind == syntax.SQLComment || prev.Kind == syntax.SQLComment) && spaceBetween(prev, t):
+			b.WriteByte(' ')
+		}
+		b.WriteString(t.Text)
+		prev, space = t, false
+		if t.Kind != syntax.SQLComment {
+			prevCode = t
+		}
+	}
+	return b.String()
+}
+
+// spaceBetween reports whether a space is kept between the tokens,
+// which are separated by whitespaces in the source.
+func spaceBetween(prev, t *syntax.SQLToken) bool {
+	switch {
+	case prev.Kind == syntax.SQLOpen, t.Kind == syntax.SQLClose, t.Kind == syntax.SQLComma, t.Kind == syntax.SQLSemicolon:
+		return false
+	}
+	return true
+}
+
+// isStringLiteral reports whether t is a string literal, e.g. 'a' and
+// E'a'. The adjacent ones must be separated by a line break, otherwise
+// PostgreSQL doesn't concatenate them.
+func isStringLiteral(t *syntax.SQLToken) bool {
+	return t != nil && t.Kind == syntax.SQLQuoted && strings.HasSuffix(t.Text, "'")
+}
+
+// lineToBlockComment converts '-- text' to '/* text */',
+// or an empty token if the text can't be in a block comment.
+func lineToBlockComment(t *syntax.SQLToken) *syntax.SQLToken {
+	text := strings.TrimSpace(strings.TrimPrefix(t.Text, "--"))
+	if text == "" || strings.Contains(text, "*/") {
+		return &syntax.SQLToken{Kind: syntax.SQLComment}
+	}
+	return &syntax.SQLToken{Kind: syntax.SQLComment, Text: "/* " + text + " */"}
+}
+
+type formatter struct {
+	tokens []*syntax.SQLToken
+	indent string
+
+	b         strings.Builder
+	parens    []bool   // the open parens, true for subqueries
+	level     int      // the level of current subquery
+	clause    string   // current clause of current subquery
+	clauses   []string // the clauses of the outer queries
+	inBetween bool     // the AND of BETWEEN is expected
+	newline   bool     // a line break is required before the next token
+}
+
+func (f *formatter) format() string {
+	space := false
+	var prev, prevCode *syntax.SQLToken
+	for i, t := range f.tokens {
+		if t.Kind == syntax.SQLSpace {
+			space = true
+			continue
+		}
+		switch {
+		case prev == nil:
+		case f.newline, isStringLiteral(prevCode) && isStringLiteral(t):
+			f.lineBreak(f.level)
+		case t.Kind == syntax.SQLClose && f.closesSubquery():
+			f.lineBreak(f.level - 1)
+		default:
+			if level, ok := f.breakBefore(i, prev); ok {
+				f.lineBreak(level)
+			} else if (space || t.Kind == syntax.SQLComment || prev.Kind == syntax.SQLComment) && spaceBetween(prev, t) {
+				f.b.WriteByte(' ')
+			}
+		}
+		f.newline = t.Kind == syntax.SQLLineComment
+		f.b.WriteString(t.Text)
+		f.track(i, t)
+		prev, space = t, false
+		if t.Kind != syntax.SQLComment && t.Kind != syntax.SQLLineComment {
+			prevCode = t
+		}
+	}
+	return f.b.String()
+}
+
+func (f *formatter) lineBreak(level int) {
+	f.b.WriteByte('\n')
+	f.b.WriteString(strings.Repeat(f.indent, level))
+}
+
+// atQueryLevel reports whether current position is directly
+// in a query, rather than in the parens of an expression.
+func (f *formatter) atQueryLevel() bool {
+	return len(f.parens) == 0 || f.parens[len(f.parens)-1]
+}
+
+func (f *formatter) closesSubquery() bool {
+	return len(f.parens) > 0 && f.parens[len(f.parens)-1]
+}
+
+// breakBefore reports whether a line break is required before the
+// token at i, and the level of the new line.
+func (f *formatter) breakBefore(i int, prev *syntax.SQLToken) (int, bool) {
+	t := f.tokens[i]
+	if t.Kind != syntax.SQLWord || !f.atQueryLevel() {
+		return 0, false
+	}
+	word := strings.ToUpper(t.Text)
+	prevWord := ""
+	if prev.Kind == syntax.SQLWord {
+		prevWord = strings.ToUpper(prev.Text)
+	}
+	switch word {
+	case "SELECT", "WHERE", "HAVING", "LIMIT", "OFFSET", "UNION", "INTERSECT", "EXCEPT",
+		"VALUES", "SET", "RETURNING", "INSERT", "UPDATE", "DELETE", "WITH":
+		if word == "UPDATE" && (prevWord == "DO" || prevWord == "FOR") {
+			return 0, false
+		}
+		if word == "SET" && prevWord == "UPDATE" {
+			return 0, false
+		}
+		return f.level, true
+	case "FROM":
+		if prevWord == "DELETE" || prevWord == "DISTINCT" {
+			return 0, false
+		}
+		return f.level, true
+	case "GROUP", "ORDER":
+		return f.level, f.nextWord(i) == "BY"
+	case "JOIN":
+		switch prevWord {
+		case "LEFT", "RIGHT", "INNER", "FULL", "CROSS", "NATURAL", "OUTER":
+			return 0, false
+		}
+		return f.level, true
+	case "LEFT", "RIGHT", "INNER", "FULL", "CROSS", "NATURAL":
+		next := f.nextWord(i)
+		if next == "OUTER" || next == "JOIN" {
+			return f.level, true
+		}
+	case "AND", "OR":
+		if f.clause == "WHERE" || f.clause == "HAVING" {
+			if word == "AND" && f.inBetween {
+				return 0, false
+			}
+			return f.level + 1, true
+		}
+	}
+	return 0, false
+}
+
+// track updates the state after writing the token at i.
+func (f *formatter) track(i int, t *syntax.SQLToken) {
+	switch t.Kind {
+	case syntax.SQLOpen:
+		sub := false
+		switch f.nextWord(i) {
+		case "SELECT", "WITH":
+			sub = true
+			f.level++
+			f.clauses = append(f.clauses, f.clause)
+			f.clause = ""
+		}
+		f.parens = append(f.parens, sub)
+	case syntax.SQLClose:
+		if f.closesSubquery() {
+			f.level--
+			f.clause = f.clauses[len(f.clauses)-1]
+			f.clauses = f.clauses[:len(f.clauses)-1]
+		}
+		if len(f.parens) > 0 {
+			f.parens = f.parens[:len(f.parens)-1]
+		}
+	case syntax.SQLWord:
+		if !f.atQueryLevel() {
+			return
+		}
+		switch word := strings.ToUpper(t.Text); word {
+		case "SELECT", "FROM", "WHERE", "HAVING", "GROUP", "ORDER", "LIMIT", "JOIN", "ON",
+			"UNION", "VALUES", "SET", "RETURNING":
+			f.clause = word
+		case "BETWEEN":
+			f.inBetween = true
+		case "AND":
+			f.inBetween = false
+		}
+	}
+}
+
+// nextWord returns the upper-cased word after the token at i.
+func (f *formatter) nextWord(i int) string {
+	for _, t := range f.tokens[i+1:] {
+		switch t.Kind {
+		case syntax.SQLSpace, syntax.SQLComment, syntax.SQLLineComment:
+			continue
+		case syntax.SQLWord:
+			return strings.ToUpper(t.Text)
+		}
+		return ""
+	}
+	return ""
+}
@@ -0,0 +1,140 @@
+package util_test
+
+import (
+	"testing"
+
+	"github.com/qjebbs/go-sqlf/v2/syntax"
+	"github.com/qjebbs/go-sqlf/v2/util"
+)
+
+func TestFormat(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{
+			name:  "clauses",
+			query: "SELECT f.id, count(*)  FROM foo AS f LEFT JOIN bar AS b ON b.id=f.id WHERE f.a = $1 AND f.b BETWEEN 1 AND 2 OR f.c IS NULL GROUP BY f.id HAVING count(*) > 1 ORDER BY f.id DESC LIMIT 10",
+			want: `SELECT f.id, count(*)
+FROM foo AS f
+LEFT JOIN bar AS b ON b.id=f.id
+WHERE f.a = $1
+  AND f.b BETWEEN 1 AND 2
+  OR f.c IS NULL
+GROUP BY f.id
+HAVING count(*) > 1
+ORDER BY f.id DESC
+LIMIT 10`,
+		},
+		{
+			name:  "subqueries and ctes",
+			query: "WITH a AS (SELECT id FROM foo WHERE x = ?) SELECT * FROM a WHERE id IN (SELECT id FROM bar WHERE y = ?) AND z = ? UNION ALL SELECT * FROM b",
+			want: `WITH a AS (
+  SELECT id
+  FROM foo
+  WHERE x = ?
+)
+SELECT *
+FROM a
+WHERE id IN (
+  SELECT id
+  FROM bar
+  WHERE y = ?
+)
+  AND z = ?
+UNION ALL
+SELECT *
+FROM b`,
+		},
+		{
+			name:  "expression parens",
+			query: "SELECT extract(year FROM t), count(*) OVER (PARTITION BY a ORDER BY b) FROM foo",
+			want: `SELECT extract(year FROM t), count(*) OVER (PARTITION BY a ORDER BY b)
+FROM foo`,
+		},
+		{
+			name:  "strings and comments",
+			query: "SELECT 'a  FROM b' -- FROM ? $1\n, \"select\" FROM t WHERE x = E'it\\'s AND' AND y = $$ WHERE $$",
+			want:  "SELECT 'a  FROM b' -- FROM ? $1\n, \"select\"\nFROM t\nWHERE x = E'it\\'s AND'\n  AND y = $$ WHERE $$",
+		},
+		{
+			name:  "adjacent string literals",
+			query: "SELECT 'a'\n  'b' FROM t",
+			want:  "SELECT 'a'\n'b'\nFROM t",
+		},
+		{
+			name:  "dml",
+			query: "INSERT INTO foo (a, b) VALUES ($1, $2) RETURNING id; UPDATE foo SET a = 1 WHERE id = 2; DELETE FROM foo WHERE id = 3",
+			want: `INSERT INTO foo (a, b)
+VALUES ($1, $2)
+RETURNING id;
+UPDATE foo
+SET a = 1
+WHERE id = 2;
+DELETE FROM foo
+WHERE id = 3`,
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			got := util.Format(tc.query)
+			if got != tc.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestMinify(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		name    string
+		query   string
+		options []util.FormatOption
+		want    string
+	}{
+		{
+			name:  "whitespaces",
+			query: "SELECT  a ,\n\tb\nFROM foo  WHERE id IN ( $1, $2 )  ",
+			want:  "SELECT a, b FROM foo WHERE id IN ($1, $2)",
+		},
+		{
+			name:  "strings",
+			query: "SELECT 'a   b', \"c  d\" ,  `e  f`, $tag$ g  h $tag$",
+			want:  "SELECT 'a   b', \"c  d\", `e  f`, $tag$ g  h $tag$",
+		},
+		{
+			name:  "comments",
+			query: "SELECT a -- the a\n  , b /* the  b */\nFROM foo --\n",
+			want:  "SELECT a /* the a */, b /* the  b */ FROM foo",
+		},
+		{
+			name:  "adjacent string literals",
+			query: "SELECT 'a'\n  'b' /* c */ E'd' FROM t",
+			want:  "SELECT 'a'\n'b' /* c */\nE'd' FROM t",
+		},
+		{
+			name:    "backslash escapes",
+			query:   "SELECT 'it\\'s   ok',   1",
+			options: []util.FormatOption{util.WithParseOptions(syntax.WithBackslashEscapes())},
+			want:    "SELECT 'it\\'s   ok', 1",
+		},
+		{
+			name:  "no space added",
+			query: "a=?AND(b)",
+			want:  "a=?AND(b)",
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			got := util.Minify(tc.query, tc.options...)
+			if got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
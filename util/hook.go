@@ -0,0 +1,159 @@
+package util
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// QueryHook is the hook of the queries executed by Scan and Count, and
+// their builder variants, which is useful for logging, metrics and tracing.
+//
+// The same *QueryEvent is passed to BeforeQuery and AfterQuery of a
+// query, so that the hook can relate them by the pointer.
+type QueryHook interface {
+	// BeforeQuery is called before the query is executed.
+	BeforeQuery(e *QueryEvent)
+	// AfterQuery is called after the rows are scanned, with the result
+	// filled in the event.
+	AfterQuery(e *QueryEvent)
+}
+
+// QueryEvent is the event of executing a query.
+type QueryEvent struct {
+	// Ctx is the context.Context of the query set by WithContext, or
+	// context.Background(). A hook can replace it in BeforeQuery for the
+	// hooks after it, and the query is executed with it if the database
+	// supports, e.g. with the one carrying a tracing span.
+	Ctx   context.Context
+	Query string
+	Args  []any
+	Start time.Time
+
+	// The results, which are set before AfterQuery is called.
+	Duration time.Duration
+	Rows     int64 // the number of rows scanned
+	Err      error
+}
+
+var (
+	globalQueryHookMu sync.RWMutex
+	globalQueryHook   QueryHook
+)
+
+// SetQueryHook sets the global QueryHook, which is used by the queries
+// without WithQueryHook. A nil hook disables it.
+func SetQueryHook(h QueryHook) {
+	globalQueryHookMu.Lock()
+	defer globalQueryHookMu.Unlock()
+	globalQueryHook = h
+}
+
+// GlobalQueryHook returns the global QueryHook set by SetQueryHook.
+func GlobalQueryHook() QueryHook {
+	globalQueryHookMu.RLock()
+	defer globalQueryHookMu.RUnlock()
+	return globalQueryHook
+}
+
+// QueryOption is the option of Scan, Count and their builder variants.
+type QueryOption func(*queryOptions)
+
+type queryOptions struct {
+	ctx     context.Context
+	hook    QueryHook
+	hookSet bool
+}
+
+// WithContext sets the context.Context of the query, which is passed to
+// the QueryHook with QueryEvent.Ctx, and used to execute the query if the
+// database supports, e.g. *sql.DB and *sql.Tx.
+func WithContext(ctx context.Context) QueryOption {
+	return func(o *queryOptions) {
+		o.ctx = ctx
+	}
+}
+
+// WithQueryHook sets the QueryHook of the query, which overrides the
+// global one. WithQueryHook(nil) disables the hooks for the query.
+func WithQueryHook(h QueryHook) QueryOption {
+	return func(o *queryOptions) {
+		o.hook = h
+		o.hookSet = true
+	}
+}
+
+func applyQueryOptions(options []QueryOption) *queryOptions {
+	opts := &queryOptions{}
+	for _, opt := range options {
+		opt(opts)
+	}
+	if !opts.hookSet {
+		opts.hook = GlobalQueryHook()
+	}
+	if opts.ctx == nil {
+		opts.ctx = context.Background()
+	}
+	return opts
+}
+
+// JoinQueryHooks returns a QueryHook calling the hooks in order,
+// the nil ones are skipped.
+func JoinQueryHooks(hooks ...QueryHook) QueryHook {
+	r := make(queryHooks, 0, len(hooks))
+	for _, h := range hooks {
+		if h != nil {
+			r = append(r, h)
+		}
+	}
+	switch len(r) {
+	case 0:
+		return nil
+	case 1:
+		return r[0]
+	}
+	return r
+}
+
+type queryHooks []QueryHook
+
+func (hooks queryHooks) BeforeQuery(e *QueryEvent) {
+	for _, h := range hooks {
+		h.BeforeQuery(e)
+	}
+}
+
+func (hooks queryHooks) AfterQuery(e *QueryEvent) {
+	for _, h := range hooks {
+		h.AfterQuery(e)
+	}
+}
+
+// runQuery runs fn with the hook of the options, fn executes the query
+// with the context, and returns the number of rows scanned.
+func runQuery(query string, args []any, opts *queryOptions, fn func(ctx context.Context) (int64, error)) error {
+	if opts.hook == nil {
+		_, err := fn(opts.ctx)
+		return err
+	}
+	e := &QueryEvent{
+		Ctx:   opts.ctx,
+		Query: query,
+		Args:  args,
+		Start: time.Now(),
+	}
+	opts.hook.BeforeQuery(e)
+	e.Rows, e.Err = fn(e.Ctx)
+	e.Duration = time.Since(e.Start)
+	opts.hook.AfterQuery(e)
+	return e.Err
+}
+
+// contextOf returns ctx, or context.Background() if it's nil, e.g. in
+// the events created by hand.
+func contextOf(ctx context.Context) context.Context {
+	if ctx == nil {
+		return context.Background()
+	}
+	return ctx
+}
@@ -0,0 +1,87 @@
+package util_test
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/qjebbs/go-sqlf/v2"
+	"github.com/qjebbs/go-sqlf/v2/syntax"
+	"github.com/qjebbs/go-sqlf/v2/util"
+)
+
+type spanKey struct{}
+
+type recordTracer struct {
+	records []string
+}
+
+func (t *recordTracer) StartSpan(ctx context.Context, name string) (context.Context, util.Span) {
+	parent, _ := ctx.Value(spanKey{}).(string)
+	t.records = append(t.records, fmt.Sprintf("start %s in %q", name, parent))
+	return context.WithValue(ctx, spanKey{}, name), &recordSpan{name: name, tracer: t}
+}
+
+type recordSpan struct {
+	name   string
+	tracer *recordTracer
+}
+
+func (s *recordSpan) SetAttribute(key string, value any) {
+	s.tracer.records = append(s.tracer.records, fmt.Sprintf("%s %s=%v", s.name, key, value))
+}
+
+func (s *recordSpan) End(err error) {
+	s.tracer.records = append(s.tracer.records, fmt.Sprintf("end %s: %v", s.name, err))
+}
+
+func TestTraceHook(t *testing.T) {
+	t.Parallel()
+	tracer := &recordTracer{}
+	hook := util.NewTraceHook(tracer)
+	ctx := context.WithValue(context.Background(), spanKey{}, "request")
+	// a hook after the TraceHook sees the context of the span
+	var built context.Context
+	after := &ctxHook{onBuildEnd: func(e *sqlf.BuildEvent) { built = e.Ctx }}
+	_, _, err := sqlf.BuildQuery(
+		sqlf.Fa("SELECT * FROM foo WHERE id = $1", 1),
+		syntax.Dollar,
+		sqlf.WithBuildHook(sqlf.JoinBuildHooks(hook, after)),
+		sqlf.WithGoContext(ctx),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := built.Value(spanKey{}); got != util.SpanBuild {
+		t.Errorf("the context after TraceHook carries %v, want %s", got, util.SpanBuild)
+	}
+	e := &util.QueryEvent{Ctx: ctx, Query: "SELECT 1"}
+	hook.BeforeQuery(e)
+	e.Rows = 1
+	hook.AfterQuery(e)
+	want := []string{
+		`start sqlf.build in "request"`,
+		"sqlf.build db.statement=SELECT * FROM foo WHERE id = $1",
+		"sqlf.build db.args=1",
+		"end sqlf.build: <nil>",
+		`start sqlf.query in "request"`,
+		"sqlf.query db.statement=SELECT 1",
+		"sqlf.query db.args=0",
+		"sqlf.query db.rows=1",
+		"end sqlf.query: <nil>",
+	}
+	if !reflect.DeepEqual(tracer.records, want) {
+		t.Errorf("got:\n%q\nwant:\n%q", tracer.records, want)
+	}
+}
+
+type ctxHook struct {
+	onBuildEnd func(e *sqlf.BuildEvent)
+}
+
+func (h *ctxHook) OnBuildStart(*sqlf.BuildEvent) {}
+
+func (h *ctxHook) OnBuildEnd(e *sqlf.BuildEvent) {
+	h.onBuildEnd(e)
+}
@@ -1,28 +1,66 @@
 package util
 
 import (
-	"bytes"
 	"database/sql/driver"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"math"
 	"reflect"
+	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/qjebbs/go-sqlf/v2/syntax"
 )
 
+// Dialect is the SQL dialect which Interpolate encodes the literals for.
+type Dialect int
+
+// Dialects.
+const (
+	// DialectStandard encodes the literals in the standard SQL, which is
+	// readable but not exactly accepted by every database.
+	DialectStandard Dialect = iota
+	// DialectPostgres encodes the literals for PostgreSQL, with the
+	// standard_conforming_strings on, which is the default since 9.1.
+	DialectPostgres
+	// DialectMySQL encodes the literals for MySQL, with the backslash
+	// escapes enabled, i.e. NO_BACKSLASH_ESCAPES is not set.
+	DialectMySQL
+	// DialectSQLite encodes the literals for SQLite.
+	DialectSQLite
+	// DialectSQLServer encodes the literals for SQL Server.
+	DialectSQLServer
+)
+
+func (d Dialect) String() string {
+	switch d {
+	case DialectPostgres:
+		return "postgres"
+	case DialectMySQL:
+		return "mysql"
+	case DialectSQLite:
+		return "sqlite"
+	case DialectSQLServer:
+		return "sqlserver"
+	default:
+		return "standard"
+	}
+}
+
 // InterpolateOption is the option of Interpolate.
 type InterpolateOption func(*interpolateOptions)
 
 type interpolateOptions struct {
-	// TimeFormat is the format of time value.
+	// TimeFormat is the format of time value, the default one of the dialect is used if empty.
 	TimeFormat string
+	Dialect    Dialect
 }
 
 func defaultInterpolateOptions() *interpolateOptions {
-	return &interpolateOptions{
-		TimeFormat: time.RFC3339,
-	}
+	return &interpolateOptions{}
 }
 
 func applyInterpolateOptions(options []InterpolateOption) *interpolateOptions {
@@ -33,109 +71,289 @@ func applyInterpolateOptions(options []InterpolateOption) *interpolateOptions {
 	return opts
 }
 
-// WithTimeFormat sets the format of time value.
+// WithTimeFormat sets the format of time value, which overrides the
+// default one of the dialect.
 func WithTimeFormat(format string) InterpolateOption {
 	return func(opts *interpolateOptions) {
 		opts.TimeFormat = format
 	}
 }
 
-// Interpolate interpolates the args into the query, use it only for
-// debug purposes to avoid SQL injection attacks.
+// WithDialect sets the dialect to encode the literals for,
+// which is DialectStandard by default.
+func WithDialect(d Dialect) InterpolateOption {
+	return func(opts *interpolateOptions) {
+		opts.Dialect = d
+	}
+}
+
+// Interpolate interpolates the args into the query.
+//
+// The literals are encoded for the dialect set by WithDialect, which
+// makes it usable for the drivers that cannot bind parameters. Note that
+// the literals of DialectStandard are meant to be readable, use it only
+// for debug purposes.
+//
+// The bindvars in string literals, quoted identifiers and comments are
+// ignored. If the query contains any $N, the ? are not bindvars, e.g.
+// the JSONB operator of PostgreSQL.
 func Interpolate(query string, args []any, options ...InterpolateOption) (string, error) {
 	opts := applyInterpolateOptions(options)
-	exprs, err := syntax.Parse(query)
-	if err != nil {
-		return "", err
+	var parseOptions []syntax.ParseOption
+	if opts.Dialect == DialectMySQL {
+		parseOptions = append(parseOptions, syntax.WithBackslashEscapes())
+	}
+	tokens := syntax.TokenizeSQL(query, parseOptions...)
+	dollar := false
+	for _, t := range tokens {
+		if _, ok := dollarIndex(t); ok {
+			dollar = true
+			break
+		}
 	}
 	b := new(strings.Builder)
-	for _, decl := range exprs.ExprList {
-		switch decl := decl.(type) {
-		case *syntax.PlainExpr:
-			b.WriteString(decl.Text)
-		case *syntax.BindVarExpr:
-			v, err := encodeValue(args[decl.Index-1], opts)
-			if err != nil {
-				return "", err
-			}
-			b.Write(v)
-		default:
-			return "", fmt.Errorf("%s: unsupported declaration", decl.Pos())
+	n := 0
+	for _, t := range tokens {
+		index, ok := dollarIndex(t)
+		if !dollar && t.Kind == syntax.SQLOther && t.Text == "?" {
+			n++
+			index, ok = n, true
 		}
+		if !ok {
+			b.WriteString(t.Text)
+			continue
+		}
+		if index < 1 || index > len(args) {
+			return "", fmt.Errorf("bindvar %s: invalid index %d, got %d args", t.Text, index, len(args))
+		}
+		v, err := encodeValue(args[index-1], opts)
+		if err != nil {
+			return "", fmt.Errorf("bindvar %s: %w", t.Text, err)
+		}
+		b.Write(v)
 	}
 	return b.String(), nil
 }
 
+// dollarIndex returns the index of the $N bindvar token.
+func dollarIndex(t *syntax.SQLToken) (int, bool) {
+	if t.Kind != syntax.SQLWord || len(t.Text) < 2 || t.Text[0] != '$' {
+		return 0, false
+	}
+	i, err := strconv.Atoi(t.Text[1:])
+	if err != nil || t.Text[1] == '+' || t.Text[1] == '-' {
+		return 0, false
+	}
+	return i, true
+}
+
+var valuerType = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+
 func encodeValue(arg any, opts *interpolateOptions) ([]byte, error) {
-	buf := bytes.NewBuffer(nil)
+	if arg == nil {
+		return []byte("NULL"), nil
+	}
+	rv := reflect.ValueOf(arg)
+	if rv.Kind() == reflect.Pointer && rv.IsNil() {
+		// like database/sql, a nil pointer is NULL, even it's a Valuer
+		return []byte("NULL"), nil
+	}
+	d := opts.Dialect
 	switch v := arg.(type) {
-	case nil:
-		buf.WriteString("NULL")
 	case driver.Valuer:
 		val, err := v.Value()
 		if err != nil {
 			return nil, err
 		}
-		enc, err := encodeValue(val, opts)
+		if val != nil && reflect.TypeOf(val).Implements(valuerType) {
+			return nil, fmt.Errorf("%T.Value() returns %T, which is a driver.Valuer", arg, val)
+		}
+		return encodeValue(val, opts)
+	case time.Time:
+		return []byte(encodeTime(v, opts)), nil
+	case json.RawMessage:
+		return []byte(encodeString(string(v), d)), nil
+	case []byte:
+		return []byte(encodeBytes(v, d)), nil
+	case json.Marshaler:
+		j, err := v.MarshalJSON()
 		if err != nil {
 			return nil, err
 		}
-		buf.Write(enc)
-	case time.Time:
-		if v.IsZero() {
-			buf.WriteString("'0000-00-00'")
-			break
+		return []byte(encodeString(string(j), d)), nil
+	}
+	switch rv.Kind() {
+	case reflect.Pointer:
+		return encodeValue(rv.Elem().Interface(), opts)
+	case reflect.Bool:
+		return []byte(encodeBool(rv.Bool(), d)), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return []byte(strconv.FormatInt(rv.Int(), 10)), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return []byte(strconv.FormatUint(rv.Uint(), 10)), nil
+	case reflect.Float32, reflect.Float64:
+		bitSize := 64
+		if rv.Kind() == reflect.Float32 {
+			bitSize = 32
 		}
-		// In SQL standard, the precision of fractional seconds in time literal is up to 6 digits.
-		v = v.Round(time.Microsecond)
-		buf.WriteRune('\'')
-		buf.WriteString(v.Format(opts.TimeFormat))
-		buf.WriteRune('\'')
-	case fmt.Stringer:
-		buf.Write(quoteStringValue(v.String()))
-	default:
-		primative := reflect.ValueOf(arg)
-		switch k := primative.Kind(); k {
-		case reflect.Bool:
-			if primative.Bool() {
-				buf.WriteString("TRUE")
-			} else {
-				buf.WriteString("FALSE")
+		s, err := encodeFloat(rv.Float(), bitSize, d)
+		if err != nil {
+			return nil, err
+		}
+		return []byte(s), nil
+	case reflect.String:
+		return []byte(encodeString(rv.String(), d)), nil
+	case reflect.Slice, reflect.Array:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			// named byte slices and byte arrays
+			b := make([]byte, rv.Len())
+			reflect.Copy(reflect.ValueOf(b), rv)
+			return []byte(encodeBytes(b, d)), nil
+		}
+		return encodeArray(rv, opts)
+	}
+	// like database/sql, the values of basic kinds are bound by their
+	// kinds, e.g. time.Duration as int64, so only the others are encoded
+	// by their String().
+	if v, ok := arg.(fmt.Stringer); ok {
+		return []byte(encodeString(v.String(), d)), nil
+	}
+	return nil, fmt.Errorf("unsupported type %T", arg)
+}
+
+func encodeBool(b bool, d Dialect) string {
+	switch d {
+	case DialectSQLite, DialectSQLServer:
+		if b {
+			return "1"
+		}
+		return "0"
+	}
+	if b {
+		return "TRUE"
+	}
+	return "FALSE"
+}
+
+func encodeFloat(f float64, bitSize int, d Dialect) (string, error) {
+	switch {
+	case math.IsNaN(f), math.IsInf(f, 0):
+		if d != DialectPostgres {
+			return "", fmt.Errorf("%v is not supported by the %s dialect", f, d)
+		}
+		s := "NaN"
+		switch {
+		case math.IsInf(f, 1):
+			s = "Infinity"
+		case math.IsInf(f, -1):
+			s = "-Infinity"
+		}
+		return "'" + s + "'::float8", nil
+	}
+	// the shortest representation that round-trips
+	return strconv.FormatFloat(f, 'g', -1, bitSize), nil
+}
+
+func encodeString(s string, d Dialect) string {
+	switch d {
+	case DialectMySQL:
+		b := new(strings.Builder)
+		b.WriteByte('\'')
+		for _, r := range s {
+			if to, ok := mysqlEscaping[r]; ok {
+				b.WriteString(to)
+				continue
+			}
+			b.WriteRune(r)
+		}
+		b.WriteByte('\'')
+		return b.String()
+	case DialectSQLServer:
+		quoted := "'" + strings.ReplaceAll(s, "'", "''") + "'"
+		for i := 0; i < len(s); i++ {
+			if s[i] >= utf8.RuneSelf {
+				// unicode string literal
+				return "N" + quoted
 			}
-		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-			buf.WriteString(fmt.Sprintf("%d", primative.Int()))
-		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-			buf.WriteString(fmt.Sprintf("%d", primative.Uint()))
-		case reflect.Float32, reflect.Float64:
-			buf.WriteString(fmt.Sprintf("%f", primative.Float()))
-		case reflect.String:
-			buf.Write(quoteStringValue(primative.String()))
-		default:
-			return nil, fmt.Errorf("unsupported type %T", arg)
-		}
-	}
-	return buf.Bytes(), nil
-}
-
-func quoteStringValue(s string) []byte {
-	buf := bytes.NewBuffer(nil)
-	buf.WriteRune('\'')
-	buf.WriteString(strings.ReplaceAll(s, "'", "''"))
-	buf.WriteRune('\'')
-	return buf.Bytes()
-}
-
-var escaping = []struct {
-	from rune
-	to   string
-}{
-	{'\x00', `\0`},
-	{'\n', `\n`},
-	{'\r', `\r`},
-	{'\b', `\b`},
-	{'\t', `\t`},
-	{'\x1a', `\Z`},
-	{'\'', "''"},
-	{'"', `\"`},
-	{'\\', `\\`},
+		}
+		return quoted
+	default:
+		return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+	}
+}
+
+var mysqlEscaping = map[rune]string{
+	'\x00': `\0`,
+	'\n':   `\n`,
+	'\r':   `\r`,
+	'\b':   `\b`,
+	'\t':   `\t`,
+	'\x1a': `\Z`,
+	'\'':   `\'`,
+	'"':    `\"`,
+	'\\':   `\\`,
+}
+
+func encodeBytes(b []byte, d Dialect) string {
+	h := strings.ToUpper(hex.EncodeToString(b))
+	switch d {
+	case DialectPostgres:
+		return `'\x` + h + `'::bytea`
+	case DialectSQLServer:
+		return "0x" + h
+	default:
+		return "X'" + h + "'"
+	}
+}
+
+func encodeTime(t time.Time, opts *interpolateOptions) string {
+	format := opts.TimeFormat
+	switch opts.Dialect {
+	case DialectPostgres:
+		t = t.Round(time.Microsecond)
+		if format == "" {
+			format = "2006-01-02 15:04:05.999999-07:00"
+		}
+	case DialectMySQL:
+		// DATETIME has no time zone, convert to UTC like
+		// the go-sql-driver/mysql does by default.
+		t = t.UTC().Round(time.Microsecond)
+		if format == "" {
+			format = "2006-01-02 15:04:05.999999"
+		}
+	case DialectSQLite:
+		if format == "" {
+			format = "2006-01-02 15:04:05.999999999-07:00"
+		}
+	case DialectSQLServer:
+		t = t.Round(100 * time.Nanosecond)
+		if format == "" {
+			format = "2006-01-02T15:04:05.9999999-07:00"
+		}
+	default:
+		// In SQL standard, the precision of fractional seconds in time literal is up to 6 digits.
+		t = t.Round(time.Microsecond)
+		if format == "" {
+			format = time.RFC3339Nano
+		}
+	}
+	return "'" + t.Format(format) + "'"
+}
+
+func encodeArray(rv reflect.Value, opts *interpolateOptions) ([]byte, error) {
+	if opts.Dialect != DialectPostgres && opts.Dialect != DialectStandard {
+		return nil, fmt.Errorf("array %s is not supported by the %s dialect", rv.Type(), opts.Dialect)
+	}
+	if rv.Len() == 0 {
+		return []byte("'{}'"), nil
+	}
+	items := make([]string, 0, rv.Len())
+	for i := 0; i < rv.Len(); i++ {
+		v, err := encodeValue(rv.Index(i).Interface(), opts)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, string(v))
+	}
+	return []byte("ARRAY[" + strings.Join(items, ", ") + "]"), nil
 }
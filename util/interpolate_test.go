@@ -0,0 +1,179 @@
+package util_test
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"math"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/qjebbs/go-sqlf/v2/util"
+)
+
+type jsonDoc struct {
+	A int `json:"a"`
+}
+
+func (d jsonDoc) MarshalJSON() ([]byte, error) {
+	return []byte(`{"a":` + strconv.Itoa(d.A) + `}`), nil
+}
+
+type point struct{ X, Y int }
+
+func (p point) String() string {
+	return "(" + strconv.Itoa(p.X) + "," + strconv.Itoa(p.Y) + ")"
+}
+
+type nestedValuer struct{}
+
+func (nestedValuer) Value() (driver.Value, error) {
+	return sql.NullInt64{Int64: 1, Valid: true}, nil
+}
+
+func TestInterpolate(t *testing.T) {
+	t.Parallel()
+	tz := time.FixedZone("", 8*3600)
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 123456789, tz)
+	var nilPtr *sql.NullString
+	testCases := []struct {
+		name    string
+		dialect util.Dialect
+		query   string
+		args    []any
+		want    string
+		wantErr bool
+	}{
+		{
+			name:  "standard",
+			query: "SELECT ?, ?, ?, ?, ?, ?",
+			args:  []any{nil, true, 1, 0.1, "it's", sql.NullString{}},
+			want:  "SELECT NULL, TRUE, 1, 0.1, 'it''s', NULL",
+		},
+		{
+			name:  "stringers of basic kinds",
+			query: "SELECT ?, ?, ?",
+			args:  []any{time.Second, util.DialectMySQL, point{1, 2}},
+			want:  "SELECT 1000000000, 2, '(1,2)'",
+		},
+		{
+			name:  "precise floats",
+			query: "SELECT ?, ?, ?",
+			args:  []any{1.0000000001, float32(0.1), 1e21},
+			want:  "SELECT 1.0000000001, 0.1, 1e+21",
+		},
+		{
+			name:  "dollar",
+			query: "SELECT $2, $1, $1",
+			args:  []any{1, "a"},
+			want:  "SELECT 'a', 1, 1",
+		},
+		{
+			name:  "ignore quoted and comments",
+			query: "SELECT '?', \"?\", ? -- ?\n/* ? */",
+			args:  []any{1},
+			want:  "SELECT '?', \"?\", 1 -- ?\n/* ? */",
+		},
+		{
+			name:    "postgres jsonb operator",
+			dialect: util.DialectPostgres,
+			query:   "SELECT data ? 'a' AND data ?| $1",
+			args:    []any{[]string{"a", "b"}},
+			want:    "SELECT data ? 'a' AND data ?| ARRAY['a', 'b']",
+		},
+		{
+			name:    "postgres",
+			dialect: util.DialectPostgres,
+			query:   "SELECT $1, $2, $3, $4, $5",
+			args:    []any{[]byte{0xde, 0xad}, []int{}, math.Inf(1), ts, time.Time{}},
+			want:    `SELECT '\xDEAD'::bytea, '{}', 'Infinity'::float8, '2024-01-02 03:04:05.123457+08:00', '0001-01-01 00:00:00+00:00'`,
+		},
+		{
+			name:    "postgres json",
+			dialect: util.DialectPostgres,
+			query:   "SELECT $1, $2",
+			args:    []any{json.RawMessage(`{"b":"it's"}`), jsonDoc{A: 1}},
+			want:    `SELECT '{"b":"it''s"}', '{"a":1}'`,
+		},
+		{
+			name:    "mysql",
+			dialect: util.DialectMySQL,
+			query:   "SELECT ?, ?, ?, ?",
+			args:    []any{"it's\n\\", []byte("a"), ts, false},
+			want:    `SELECT 'it\'s\n\\', X'61', '2024-01-01 19:04:05.123457', FALSE`,
+		},
+		{
+			name:    "mysql backslash quoted",
+			dialect: util.DialectMySQL,
+			query:   `SELECT 'a\' ?', ?`,
+			args:    []any{1},
+			want:    `SELECT 'a\' ?', 1`,
+		},
+		{
+			name:    "sqlite",
+			dialect: util.DialectSQLite,
+			query:   "SELECT ?, ?, ?",
+			args:    []any{true, [2]byte{1, 2}, ts},
+			want:    "SELECT 1, X'0102', '2024-01-02 03:04:05.123456789+08:00'",
+		},
+		{
+			name:    "sqlserver",
+			dialect: util.DialectSQLServer,
+			query:   "SELECT ?, ?, ?, ?",
+			args:    []any{"ok", "你好", []byte{0xff}, ts},
+			want:    "SELECT 'ok', N'你好', 0xFF, '2024-01-02T03:04:05.1234568+08:00'",
+		},
+		{
+			name:  "nil pointer",
+			query: "SELECT ?",
+			args:  []any{nilPtr},
+			want:  "SELECT NULL",
+		},
+		{
+			name:    "nested valuer",
+			query:   "SELECT ?",
+			args:    []any{nestedValuer{}},
+			wantErr: true,
+		},
+		{
+			name:    "array of mysql",
+			dialect: util.DialectMySQL,
+			query:   "SELECT ?",
+			args:    []any{[]int{1}},
+			wantErr: true,
+		},
+		{
+			name:    "nan of sqlite",
+			dialect: util.DialectSQLite,
+			query:   "SELECT ?",
+			args:    []any{math.NaN()},
+			wantErr: true,
+		},
+		{
+			name:    "missing arg",
+			query:   "SELECT ?, ?",
+			args:    []any{1},
+			wantErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := util.Interpolate(tc.query, tc.args, util.WithDialect(tc.dialect))
+			if tc.wantErr {
+				if err == nil {
+					t.Fatalf("want error, got %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tc.want)
+			}
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package util
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 
@@ -16,35 +17,54 @@ type QueryAble interface {
 	QueryRow(query string, args ...any) *sql.Row
 }
 
+// queryContextAble is implemented by *sql.DB, *sql.Tx, etc.
+type queryContextAble interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
 // NewScanDestFunc is the function to create a new scan destination,
 // returning the destination and its fields to scan.
 type NewScanDestFunc[T any] func() (T, []any)
 
 // ScanBuilder is like Scan, but it builds query from sqlf.Builder
-func ScanBuilder[T any](db QueryAble, b sqlf.QueryBuilder, bindVarStyle syntax.BindVarStyle, fn NewScanDestFunc[T]) ([]T, error) {
+func ScanBuilder[T any](db QueryAble, b sqlf.QueryBuilder, bindVarStyle syntax.BindVarStyle, fn NewScanDestFunc[T], options ...QueryOption) ([]T, error) {
 	query, args, err := b.BuildQuery(bindVarStyle)
 	if err != nil {
 		return nil, err
 	}
-	return Scan[T](db, query, args, fn)
+	return Scan[T](db, query, args, fn, options...)
 }
 
 // Scan scans query rows with scanner
-func Scan[T any](db QueryAble, query string, args []any, fn NewScanDestFunc[T]) ([]T, error) {
-	rows, err := db.Query(query, args...)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
+func Scan[T any](db QueryAble, query string, args []any, fn NewScanDestFunc[T], options ...QueryOption) ([]T, error) {
 	var results []T
-	for rows.Next() {
-		dest, fields := fn()
-		err = ScanRow(rows, fields...)
+	err := runQuery(query, args, applyQueryOptions(options), func(ctx context.Context) (int64, error) {
+		var (
+			rows *sql.Rows
+			err  error
+		)
+		if cdb, ok := db.(queryContextAble); ok {
+			rows, err = cdb.QueryContext(ctx, query, args...)
+		} else {
+			rows, err = db.Query(query, args...)
+		}
 		if err != nil {
-			return nil, err
+			return 0, err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			dest, fields := fn()
+			err = ScanRow(rows, fields...)
+			if err != nil {
+				return int64(len(results)), err
+			}
+			results = append(results, dest)
 		}
-		results = append(results, dest)
+		return int64(len(results)), rows.Err()
+	})
+	if err != nil {
+		return nil, err
 	}
 	return results, nil
 }
@@ -65,21 +85,33 @@ func ScanRow(rows *sql.Rows, dest ...any) error {
 }
 
 // CountBuilder is like Count, but it builds query from sqlf.Builder.
-func CountBuilder(db QueryAble, b sqlf.QueryBuilder, bindVarStyle syntax.BindVarStyle) (count int64, err error) {
+func CountBuilder(db QueryAble, b sqlf.QueryBuilder, bindVarStyle syntax.BindVarStyle, options ...QueryOption) (count int64, err error) {
 	query, args, err := b.BuildQuery(bindVarStyle)
 	if err != nil {
 		return 0, err
 	}
-	return Count(db, query, args)
+	return Count(db, query, args, options...)
 }
 
 // Count count the number of rows of the query.
-func Count(db QueryAble, query string, args []any) (count int64, err error) {
+func Count(db QueryAble, query string, args []any, options ...QueryOption) (count int64, err error) {
 	query = fmt.Sprintf(`SELECT COUNT(1) FROM (%s) list`, query)
-	err = db.QueryRow(query, args...).Scan(&count)
-	if err == sql.ErrNoRows {
-		return 0, nil
-	}
+	err = runQuery(query, args, applyQueryOptions(options), func(ctx context.Context) (int64, error) {
+		var row *sql.Row
+		if cdb, ok := db.(queryContextAble); ok {
+			row = cdb.QueryRowContext(ctx, query, args...)
+		} else {
+			row = db.QueryRow(query, args...)
+		}
+		err := row.Scan(&count)
+		if err == sql.ErrNoRows {
+			return 0, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		return 1, nil
+	})
 	if err != nil {
 		query, _ := Interpolate(query, args)
 		return 0, fmt.Errorf("%w: %s", err, query)
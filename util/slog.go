@@ -0,0 +1,75 @@
+//go:build go1.21
+
+package util
+
+import (
+	"log/slog"
+
+	"github.com/qjebbs/go-sqlf/v2"
+)
+
+// SlogHook is a sqlf.BuildHook and QueryHook, which logs the builds
+// and queries with the log/slog logger.
+//
+// The successful builds and queries are logged at the Level, and the
+// failed ones at slog.LevelError, with the contexts of the events, so
+// that the handlers see the request-scoped values.
+type SlogHook struct {
+	Logger *slog.Logger // slog.Default() if nil
+	Level  slog.Level
+}
+
+var (
+	_ sqlf.BuildHook = (*SlogHook)(nil)
+	_ QueryHook      = (*SlogHook)(nil)
+)
+
+// NewSlogHook returns a new SlogHook logging at slog.LevelDebug.
+func NewSlogHook(l *slog.Logger) *SlogHook {
+	return &SlogHook{Logger: l, Level: slog.LevelDebug}
+}
+
+// OnBuildStart implements sqlf.BuildHook.
+func (h *SlogHook) OnBuildStart(*sqlf.BuildEvent) {}
+
+// OnBuildEnd implements sqlf.BuildHook.
+func (h *SlogHook) OnBuildEnd(e *sqlf.BuildEvent) {
+	if e.Err != nil {
+		h.logger().LogAttrs(contextOf(e.Ctx), slog.LevelError, "sqlf: build failed",
+			slog.Duration("duration", e.Duration),
+			slog.String("error", e.Err.Error()),
+		)
+		return
+	}
+	h.logger().LogAttrs(contextOf(e.Ctx), h.Level, "sqlf: build",
+		slog.String("query", e.Query),
+		slog.Int("args", len(e.Args)),
+		slog.Duration("duration", e.Duration),
+	)
+}
+
+// BeforeQuery implements QueryHook.
+func (h *SlogHook) BeforeQuery(*QueryEvent) {}
+
+// AfterQuery implements QueryHook.
+func (h *SlogHook) AfterQuery(e *QueryEvent) {
+	attrs := []slog.Attr{
+		slog.String("query", e.Query),
+		slog.Int("args", len(e.Args)),
+		slog.Duration("duration", e.Duration),
+		slog.Int64("rows", e.Rows),
+	}
+	level := h.Level
+	if e.Err != nil {
+		level = slog.LevelError
+		attrs = append(attrs, slog.String("error", e.Err.Error()))
+	}
+	h.logger().LogAttrs(contextOf(e.Ctx), level, "sqlf: query", attrs...)
+}
+
+func (h *SlogHook) logger() *slog.Logger {
+	if h.Logger != nil {
+		return h.Logger
+	}
+	return slog.Default()
+}
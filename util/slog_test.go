@@ -0,0 +1,60 @@
+//go:build go1.21
+
+package util_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/qjebbs/go-sqlf/v2"
+	"github.com/qjebbs/go-sqlf/v2/syntax"
+	"github.com/qjebbs/go-sqlf/v2/util"
+)
+
+func TestSlogHook(t *testing.T) {
+	t.Parallel()
+	buf := new(bytes.Buffer)
+	handler := slog.NewTextHandler(buf, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+		ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey || a.Key == "duration" {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})
+	hook := util.NewSlogHook(slog.New(requestHandler{handler}))
+	ctx := context.WithValue(context.Background(), requestKey{}, "r1")
+	_, _, err := sqlf.BuildQuery(
+		sqlf.Fa("id = $1", 1), syntax.Dollar,
+		sqlf.WithBuildHook(hook), sqlf.WithGoContext(ctx),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hook.AfterQuery(&util.QueryEvent{Query: "SELECT 1", Err: errors.New("oops")})
+	want := []string{
+		`level=DEBUG msg="sqlf: build" query="id = $1" args=1 request=r1`,
+		`level=ERROR msg="sqlf: query" query="SELECT 1" args=0 rows=0 error=oops request=<nil>`,
+	}
+	got := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("got:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+type requestKey struct{}
+
+// requestHandler logs the request of the context.
+type requestHandler struct {
+	slog.Handler
+}
+
+func (h requestHandler) Handle(ctx context.Context, r slog.Record) error {
+	r.AddAttrs(slog.Any("request", ctx.Value(requestKey{})))
+	return h.Handler.Handle(ctx, r)
+}
@@ -0,0 +1,98 @@
+package util
+
+import (
+	"context"
+	"sync"
+
+	"github.com/qjebbs/go-sqlf/v2"
+)
+
+// Tracer is the interface of tracers, which is easy to implement on top
+// of the tracing libraries, e.g. OpenTelemetry, without depending on them:
+//
+//	func (t otelTracer) StartSpan(ctx context.Context, name string) (context.Context, util.Span) {
+//		ctx, span := t.tracer.Start(ctx, name)
+//		return ctx, otelSpan{span}
+//	}
+type Tracer interface {
+	// StartSpan starts a span with the name as a child of the span in
+	// ctx if any, and returns the context carrying the new span.
+	StartSpan(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span is the interface of tracing spans.
+type Span interface {
+	// SetAttribute sets an attribute of the span.
+	SetAttribute(key string, value any)
+	// End ends the span, with the error if the operation failed.
+	End(err error)
+}
+
+// Span names and attribute keys reported by TraceHook.
+const (
+	SpanBuild = "sqlf.build"
+	SpanQuery = "sqlf.query"
+
+	AttrStatement = "db.statement"
+	AttrArgs      = "db.args"
+	AttrRows      = "db.rows"
+)
+
+// TraceHook is a sqlf.BuildHook and QueryHook, which reports the builds
+// and queries as spans of the Tracer, parented by the spans in the
+// contexts set by sqlf.WithGoContext and WithContext.
+type TraceHook struct {
+	tracer Tracer
+	spans  sync.Map // event pointer -> Span
+}
+
+var (
+	_ sqlf.BuildHook = (*TraceHook)(nil)
+	_ QueryHook      = (*TraceHook)(nil)
+)
+
+// NewTraceHook returns a new TraceHook with the tracer.
+func NewTraceHook(t Tracer) *TraceHook {
+	return &TraceHook{tracer: t}
+}
+
+// OnBuildStart implements sqlf.BuildHook.
+func (h *TraceHook) OnBuildStart(e *sqlf.BuildEvent) {
+	ctx, span := h.tracer.StartSpan(contextOf(e.Ctx), SpanBuild)
+	e.Ctx = ctx
+	h.spans.Store(e, span)
+}
+
+// OnBuildEnd implements sqlf.BuildHook.
+func (h *TraceHook) OnBuildEnd(e *sqlf.BuildEvent) {
+	v, ok := h.spans.LoadAndDelete(e)
+	if !ok {
+		return
+	}
+	span := v.(Span)
+	if e.Err == nil {
+		span.SetAttribute(AttrStatement, e.Query)
+		span.SetAttribute(AttrArgs, len(e.Args))
+	}
+	span.End(e.Err)
+}
+
+// BeforeQuery implements QueryHook.
+func (h *TraceHook) BeforeQuery(e *QueryEvent) {
+	ctx, span := h.tracer.StartSpan(contextOf(e.Ctx), SpanQuery)
+	e.Ctx = ctx
+	span.SetAttribute(AttrStatement, e.Query)
+	span.SetAttribute(AttrArgs, len(e.Args))
+	h.spans.Store(e, span)
+}
+
+// AfterQuery implements QueryHook.
+func (h *TraceHook) AfterQuery(e *QueryEvent) {
+	v, ok := h.spans.LoadAndDelete(e)
+	if !ok {
+		return
+	}
+	span := v.(Span)
+	span.SetAttribute(AttrRows, e.Rows)
+	span.End(e.Err)
+}
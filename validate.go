@@ -0,0 +1,347 @@
+package sqlf
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/qjebbs/go-sqlf/v2/syntax"
+)
+
+// ValidationErrors is the list of errors reported by Validate,
+// each of them is a *BuildError.
+type ValidationErrors []error
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Unwrap returns the errors.
+func (e ValidationErrors) Unwrap() []error {
+	return e
+}
+
+// Validate checks the whole fragment tree statically, which is useful to
+// find errors ahead of time, for example, in a unit test or at init.
+//
+// It reports syntax errors, references to nonexistent #fN, #argN or $N,
+// args or fragments never referenced, calls of unknown functions, and
+// functions incompatible with #join, without building the fragments,
+// so no args are committed and no real values are needed.
+//
+// Since the behaviour of a custom function is unknown, the usage of args
+// and fragments is not checked for the fragment calling any. It's neither
+// checked for fragments whose UsagePolicy is not UsageStrict.
+//
+// The returned error is ValidationErrors if not nil.
+func Validate(b FragmentBuilder) error {
+	return ValidateContext(NewContext(syntax.Dollar), b)
+}
+
+// ValidateContext is like Validate, but it resolves functions from ctx,
+// so that the custom functions added by ContextWithFuncs are recognized.
+func ValidateContext(ctx *Context, b FragmentBuilder) error {
+	if b == nil {
+		return nil
+	}
+	v := &validator{ctx: ctx}
+	Walk(b, v)
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return v.errs
+}
+
+var _ Visitor = (*validator)(nil)
+
+type validator struct {
+	ctx  *Context
+	errs ValidationErrors
+
+	stack []*Fragment // visiting builders, nil for non-fragment ones
+}
+
+// Visit implements Visitor
+func (v *validator) Visit(b FragmentBuilder) Visitor {
+	if b == nil {
+		v.stack = v.stack[:len(v.stack)-1]
+		return nil
+	}
+	f, _ := b.(*Fragment)
+	if f != nil {
+		v.validateFragment(f)
+	}
+	v.stack = append(v.stack, f)
+	return v
+}
+
+func (v *validator) path() []ErrorFrame {
+	var r []ErrorFrame
+	for _, f := range v.stack {
+		if f != nil {
+			r = append(r, ErrorFrame{Raw: f.Raw})
+		}
+	}
+	return r
+}
+
+func (v *validator) report(f *Fragment, pos syntax.Pos, err error) {
+	var se *syntax.Error
+	if errors.As(err, &se) && pos.Line() == 0 {
+		pos = se.Pos
+	}
+	v.errs = append(v.errs, &BuildError{
+		Raw:  f.Raw,
+		Pos:  pos,
+		Path: v.path(),
+		Err:  err,
+	})
+}
+
+func (v *validator) validateFragment(f *Fragment) {
+	if err := f.checkNames(); err != nil {
+		v.report(f, syntax.Pos{}, err)
+		return
+	}
+	clause, err := v.ctx.parse(f.Raw)
+	if err != nil {
+		v.report(f, syntax.Pos{}, err)
+		return
+	}
+	fv := &fragmentValidator{
+		ctx:       v.ctx,
+		f:         f,
+		args:      make([]bool, len(f.Args)),
+		fragments: make([]bool, len(f.Fragments)),
+	}
+	failed := false
+	for _, expr := range clause.ExprList {
+		if err := fv.expr(expr); err != nil {
+			failed = true
+			v.report(f, expr.Pos(), err)
+		}
+	}
+	if failed || fv.usageUnknown || v.ctx.usagePolicy(f) != UsageStrict {
+		return
+	}
+	if err := fv.unused(); err != nil {
+		v.report(f, syntax.Pos{}, err)
+	}
+}
+
+// fragmentValidator validates the references of a fragment.
+type fragmentValidator struct {
+	ctx *Context
+	f   *Fragment
+
+	args         []bool // whether the args are referenced
+	fragments    []bool // whether the fragments are referenced
+	usageUnknown bool   // whether a custom function is called
+}
+
+func (v *fragmentValidator) expr(expr syntax.Expr) error {
+	switch expr := expr.(type) {
+	case *syntax.PlainExpr:
+		return nil
+	case *syntax.BindVarExpr:
+		if expr.Index < 1 || expr.Index > len(v.args) {
+			return &BindVarError{Index: expr.Index, NArgs: len(v.args)}
+		}
+		v.args[expr.Index-1] = true
+		return nil
+	case *syntax.FuncCallExpr:
+		return v.call(expr.Name, expr.Args, false)
+	case *syntax.FuncExpr:
+		return fmt.Errorf("unexpected function value #%s, forgot to call it?", expr.Name)
+	case *syntax.IfExpr:
+		if err := v.call(expr.Cond.Name, expr.Cond.Args, true); err != nil {
+			return fmt.Errorf("#if condition: %w", err)
+		}
+		for _, exprs := range [][]syntax.Expr{expr.Then, expr.Else} {
+			for _, e := range exprs {
+				if err := v.expr(e); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown expression type %T", expr)
+	}
+}
+
+func (v *fragmentValidator) call(name string, args []any, cond bool) error {
+	fn, ok := v.ctx.fn(name)
+	if !ok {
+		return &UnknownFuncError{Name: name}
+	}
+	switch {
+	case cond && fn.nOut == 0:
+		return fmt.Errorf("#%s returns nothing, which cannot be used as a condition", name)
+	case !cond && fn.outBool:
+		return fmt.Errorf("#%s returns bool, which can be used only as a condition of #if", name)
+	}
+	argv, err := callArgs(nil, fn, args)
+	if err != nil {
+		return err
+	}
+	if err := v.nested(args); err != nil {
+		return err
+	}
+	if !fn.builtin {
+		// only functions with the context can access the args and fragments
+		if fn.inContextFirst {
+			v.usageUnknown = true
+		}
+		return nil
+	}
+	switch name {
+	case "f", "fragment":
+		return v.reference(v.fragments, v.f.FragmentNames, argv[1].Interface())
+	case "arg":
+		return v.reference(v.args, v.f.ArgNames, argv[1].Interface())
+	case "join":
+		indexes := make([]int, 0, len(argv)-3)
+		for _, a := range argv[3:] {
+			indexes = append(indexes, int(a.Int()))
+		}
+		return v.join(argv[1].String(), indexes)
+	}
+	return nil
+}
+
+// nested validates the nested function calls and values in args.
+func (v *fragmentValidator) nested(args []any) error {
+	for _, arg := range args {
+		switch arg := arg.(type) {
+		case *syntax.FuncCallExpr:
+			if err := v.call(arg.Name, arg.Args, false); err != nil {
+				return err
+			}
+		case *syntax.FuncExpr:
+			if _, ok := v.ctx.fn(arg.Name); !ok {
+				return &UnknownFuncError{Name: arg.Name}
+			}
+			// the args it's called with are unknown
+			v.usageUnknown = true
+		}
+	}
+	return nil
+}
+
+func (v *fragmentValidator) reference(list []bool, names []string, ref any) error {
+	i, err := refIndex(ref, names)
+	if err != nil {
+		return err
+	}
+	if i < 1 || i > len(list) {
+		return &InvalidIndexError{Index: i}
+	}
+	list[i-1] = true
+	return nil
+}
+
+// join validates #join(tmpl, separator, indexes...) the same way as funcJoin.
+func (v *fragmentValidator) join(tmpl string, indexes []int) error {
+	var from, to int
+	switch len(indexes) {
+	case 0:
+	case 1:
+		from = indexes[0]
+	case 2:
+		from, to = indexes[0], indexes[1]
+	default:
+		return fmt.Errorf(
+			"too many args for #join: want 2-4 got %d",
+			len(indexes)+2,
+		)
+	}
+	if to > 0 && from > to {
+		return fmt.Errorf("invalid index range %d to %d", from, to)
+	}
+	c, err := v.ctx.parse(tmpl)
+	if err != nil {
+		return fmt.Errorf("parse join template '%s': %w", tmpl, err)
+	}
+	// lists iterated by the join, nil for the ones of custom functions
+	var lists [][]bool
+	for _, expr := range c.ExprList {
+		fn, ok := expr.(*syntax.FuncExpr)
+		if !ok {
+			if err := v.expr(expr); err != nil {
+				return err
+			}
+			continue
+		}
+		f, ok := v.ctx.fn(fn.Name)
+		if !ok {
+			return &UnknownFuncError{Name: fn.Name}
+		}
+		if err := f.JoinCompatibilityError(); err != nil {
+			return fmt.Errorf("function #%s is incompatible with #join: %w", fn.Name, err)
+		}
+		switch {
+		case f.builtin && (fn.Name == "f" || fn.Name == "fragment"):
+			lists = append(lists, v.fragments)
+		case f.builtin && fn.Name == "arg":
+			lists = append(lists, v.args)
+		default:
+			lists = append(lists, nil)
+		}
+	}
+	if len(lists) == 0 {
+		return fmt.Errorf("no function in join template '%s' (e.g.: #f, not #f1)", tmpl)
+	}
+	// the join stops at the shortest list
+	n := -1
+	for _, list := range lists {
+		if list == nil {
+			v.usageUnknown = true
+			return nil
+		}
+		if n < 0 || len(list) < n {
+			n = len(list)
+		}
+	}
+	start, end := from, to
+	if start <= 0 {
+		start = 1
+	}
+	if end <= 0 {
+		end = n
+	}
+	if from > 0 && from > n {
+		return &InvalidIndexError{Index: from}
+	}
+	if to > n {
+		return &InvalidIndexError{Index: n + 1}
+	}
+	for _, list := range lists {
+		for i := start; i <= end; i++ {
+			list[i-1] = true
+		}
+	}
+	return nil
+}
+
+func (v *fragmentValidator) unused() error {
+	err := &UnusedError{}
+	for i, used := range v.args {
+		if _, optional := argValue(v.f.Args[i]); !used && !optional {
+			err.Args = append(err.Args, i+1)
+		}
+	}
+	for i, used := range v.fragments {
+		if _, optional := fragmentValue(v.f.Fragments[i]); !used && !optional {
+			err.Fragments = append(err.Fragments, i+1)
+		}
+	}
+	if len(err.Args) == 0 && len(err.Fragments) == 0 {
+		return nil
+	}
+	return err
+}
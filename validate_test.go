@@ -0,0 +1,132 @@
+package sqlf_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/qjebbs/go-sqlf/v2"
+	"github.com/qjebbs/go-sqlf/v2/syntax"
+)
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+	testCases := []struct {
+		name     string
+		fragment sqlf.FragmentBuilder
+		wantErrs []any
+	}{
+		{
+			name: "valid",
+			fragment: sqlf.Ff(
+				"SELECT * FROM foo WHERE #join('#fragment', ' AND ')",
+				sqlf.Fa("baz = $1", true),
+				sqlf.Fa("bar BETWEEN ? AND ?", 1, 100),
+				sqlf.Fa("id IN (#join('#arg', ', '))", 1, 2, 3),
+				sqlf.F("#f1, #join('#f', ', ', 2)").
+					WithFragments(sqlf.F("a"), sqlf.F("b"), sqlf.F("c")),
+			),
+		},
+		{
+			name:     "syntax error",
+			fragment: sqlf.Ff("#f1", sqlf.Fa("$1, ?", 1, 2)),
+			wantErrs: []any{new(*syntax.Error)},
+		},
+		{
+			name:     "nonexistent references",
+			fragment: sqlf.Fa("#f1 $2 #arg3", 1, 2),
+			wantErrs: []any{new(*sqlf.InvalidIndexError), new(*sqlf.InvalidIndexError)},
+		},
+		{
+			name:     "bindvar",
+			fragment: sqlf.Fa("$1 $2", 1),
+			wantErrs: []any{new(*sqlf.BindVarError)},
+		},
+		{
+			name: "unused",
+			fragment: sqlf.Ff(
+				"#f1",
+				sqlf.Fa("#join('#arg', ',', 2)", 1, 2, 3),
+				sqlf.F("not referenced"),
+			),
+			wantErrs: []any{new(*sqlf.UnusedError), new(*sqlf.UnusedError)},
+		},
+		{
+			name:     "unknown function",
+			fragment: sqlf.F("#foo(1) #join('#bar', ',')"),
+			wantErrs: []any{new(*sqlf.UnknownFuncError), new(*sqlf.UnknownFuncError)},
+		},
+		{
+			name:     "join incompatible",
+			fragment: sqlf.F("#join('#join', ',')"),
+			wantErrs: []any{new(error)},
+		},
+		{
+			name:     "join range",
+			fragment: sqlf.Fa("#join('#arg', ',', 2, 4)", 1, 2, 3),
+			wantErrs: []any{new(*sqlf.InvalidIndexError)},
+		},
+		{
+			name:     "wrong args",
+			fragment: sqlf.F("#f('a')"),
+			wantErrs: []any{new(error)},
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			err := sqlf.Validate(tc.fragment)
+			if len(tc.wantErrs) == 0 {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			var errs sqlf.ValidationErrors
+			if !errors.As(err, &errs) {
+				t.Fatalf("want sqlf.ValidationErrors, got %v", err)
+			}
+			if len(errs) != len(tc.wantErrs) {
+				t.Fatalf("want %d errors, got %d: %v", len(tc.wantErrs), len(errs), err)
+			}
+			for i, want := range tc.wantErrs {
+				var be *sqlf.BuildError
+				if !errors.As(errs[i], &be) {
+					t.Errorf("want *sqlf.BuildError, got %T", errs[i])
+				}
+				if !errors.As(errs[i], want) {
+					t.Errorf("want %T, got %v", want, errs[i])
+				}
+			}
+		})
+	}
+}
+
+func TestValidateContext(t *testing.T) {
+	t.Parallel()
+	ctx, err := sqlf.ContextWithFuncs(sqlf.NewContext(syntax.Dollar), sqlf.FuncMap{
+		"_id": func(ctx *sqlf.Context, i int) (string, error) {
+			return "", sqlf.ErrInvalidIndex
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fragment := sqlf.Fa("#join('#_id', ', ')", 1) // usage is unknown with custom functions
+	if err := sqlf.ValidateContext(ctx, fragment); err != nil {
+		t.Fatal(err)
+	}
+	if err := sqlf.Validate(fragment); err == nil {
+		t.Fatal("want unknown function error, got nil")
+	}
+}
+
+func ExampleValidate() {
+	err := sqlf.Validate(sqlf.Ff(
+		"SELECT * FROM foo WHERE #f1",
+		sqlf.Fa("id = $1 AND #foo(1)", 1, 2),
+	))
+	fmt.Println(err)
+	// Output:
+	// build 'id = $1 AND #foo(1)' (1:13): unknown function #foo
+}
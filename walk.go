@@ -0,0 +1,68 @@
+package sqlf
+
+// Visitor visits the builders of a fragment tree, see Walk.
+type Visitor interface {
+	// Visit is invoked for each builder encountered by Walk.
+	// If the result visitor w is not nil, Walk visits each of the
+	// children of b with the visitor w, followed by a call of
+	// w.Visit(nil).
+	Visit(b FragmentBuilder) (w Visitor)
+}
+
+// Parent is the interface implemented by the FragmentBuilders composed
+// of other builders, e.g. *Fragment, which exposes the children to Walk.
+//
+// Implement it for a custom FragmentBuilder to make its children visible
+// to tools built on Walk, e.g. the dependency collection of *sqlb.QueryBuilder.
+type Parent interface {
+	// Children returns the child builders in order.
+	Children() []FragmentBuilder
+}
+
+var _ Parent = (*Fragment)(nil)
+
+// Children implements Parent.
+func (f *Fragment) Children() []FragmentBuilder {
+	if f == nil {
+		return nil
+	}
+	return f.Fragments
+}
+
+// Walk traverses a fragment tree in depth-first order: It starts by
+// calling v.Visit(b); b must not be nil. If the visitor w returned by
+// v.Visit(b) is not nil, Walk is invoked recursively with visitor w for
+// each of the non-nil children of b, followed by a call of w.Visit(nil).
+//
+// Only the children of builders implementing Parent are visited.
+func Walk(b FragmentBuilder, v Visitor) {
+	if v = v.Visit(b); v == nil {
+		return
+	}
+	if p, ok := b.(Parent); ok {
+		for _, child := range p.Children() {
+			if child == nil {
+				continue
+			}
+			Walk(child, v)
+		}
+	}
+	v.Visit(nil)
+}
+
+type inspector func(FragmentBuilder) bool
+
+func (f inspector) Visit(b FragmentBuilder) Visitor {
+	if f(b) {
+		return f
+	}
+	return nil
+}
+
+// Inspect traverses a fragment tree in depth-first order: It starts by
+// calling f(b); b must not be nil. If f returns true, Inspect invokes f
+// recursively for each of the non-nil children of b, followed by a call
+// of f(nil).
+func Inspect(b FragmentBuilder, f func(FragmentBuilder) bool) {
+	Walk(b, inspector(f))
+}
@@ -0,0 +1,69 @@
+package sqlf_test
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/qjebbs/go-sqlf/v2"
+	"github.com/qjebbs/go-sqlf/v2/sqlb"
+)
+
+type wrapped struct {
+	sqlf.FragmentBuilder
+}
+
+func (w *wrapped) Children() []sqlf.FragmentBuilder {
+	return []sqlf.FragmentBuilder{w.FragmentBuilder}
+}
+
+func TestWalk(t *testing.T) {
+	t.Parallel()
+	foo := sqlb.NewTableAliased("foo", "f")
+	tree := sqlf.Ff(
+		"#f1 #f2 #f3",
+		sqlf.Fa("a = $1", 1),
+		&wrapped{sqlf.Fa("b = $1", 2)},
+		foo.Column("id"),
+	)
+	got := make([]string, 0)
+	sqlf.Inspect(tree, func(b sqlf.FragmentBuilder) bool {
+		switch b := b.(type) {
+		case nil:
+			got = append(got, "end")
+		case *sqlf.Fragment:
+			got = append(got, b.Raw)
+		default:
+			got = append(got, fmt.Sprintf("%T", b))
+		}
+		return true
+	})
+	want := []string{
+		"#f1 #f2 #f3",
+		"a = $1", "end",
+		"*sqlf_test.wrapped", "b = $1", "end", "end",
+		"*sqlb.Column", "f.id", "end", "sqlb.Table", "end", "end",
+		"end",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func ExampleInspect() {
+	fragment := sqlf.Ff(
+		"SELECT * FROM foo WHERE #join('#fragment', ' AND ')",
+		sqlf.Fa("baz = $1", true),
+		sqlf.Fa("bar BETWEEN ? AND ?", 1, 100),
+	)
+	nArgs := 0
+	sqlf.Inspect(fragment, func(b sqlf.FragmentBuilder) bool {
+		if f, ok := b.(*sqlf.Fragment); ok {
+			nArgs += len(f.Args)
+		}
+		return true
+	})
+	fmt.Println(nArgs)
+	// Output:
+	// 3
+}